@@ -7,6 +7,7 @@ import (
 	"github.com/google/go-github/github"
 	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
 	"net/http"
 	"os"
 	"strings"
@@ -14,7 +15,11 @@ import (
 	"time"
 )
 
-const _defaultWorkerCount = 20
+const (
+	_defaultWorkerCount = 20
+	_compareBatchSize   = 25
+	_nodesBatchSize     = 100
+)
 
 type GitHub struct {
 	enterpriseEndpoint string
@@ -24,6 +29,15 @@ type GitHub struct {
 	httpClient  *http.Client
 	context     context.Context
 	workerCount int
+
+	appId             int64
+	appInstallationId int64
+	appPrivateKey     []byte
+	appPrivateKeyPath string
+
+	auditor      Auditor
+	identityOnce sync.Once
+	identity     struct{ User, Host, Viewer string }
 }
 
 type Option = func(*GitHub)
@@ -52,6 +66,25 @@ func WithWorkerCount(workerCount int) Option {
 	}
 }
 
+// WithGitHubApp authenticates the session as a GitHub App installation instead of using the GITHUB_TOKEN environment
+// variable. Installation tokens are minted from the provided PEM encoded private key and refreshed before expiry.
+func WithGitHubApp(appId, installationId int64, privateKey []byte) Option {
+	return func(session *GitHub) {
+		session.appId = appId
+		session.appInstallationId = installationId
+		session.appPrivateKey = privateKey
+	}
+}
+
+// WithGitHubAppKeyFile behaves like WithGitHubApp but reads the PEM encoded private key from the provided path.
+func WithGitHubAppKeyFile(appId, installationId int64, privateKeyPath string) Option {
+	return func(session *GitHub) {
+		session.appId = appId
+		session.appInstallationId = installationId
+		session.appPrivateKeyPath = privateKeyPath
+	}
+}
+
 func NewSession(opts ...Option) (*GitHub, error) {
 	inst := new(GitHub)
 	for _, opt := range opts {
@@ -66,13 +99,13 @@ func NewSession(opts ...Option) (*GitHub, error) {
 		inst.workerCount = _defaultWorkerCount
 	}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if len(strings.TrimSpace(token)) == 0 {
-		return nil, fmt.Errorf("a GITHUB_TOKEN environment variable needs to be set")
+	ts, err := inst.tokenSource()
+	if err != nil {
+		return nil, err
 	}
 
 	if inst.httpClient == nil {
-		ghRoundTripper, err := helpers.NewGitHubRoundTripper(inst.context, token)
+		ghRoundTripper, err := helpers.NewGitHubRoundTripper(inst.context, ts)
 		if err != nil {
 			return nil, err
 		}
@@ -91,6 +124,30 @@ func NewSession(opts ...Option) (*GitHub, error) {
 	}
 	return inst, nil
 }
+
+func (gh *GitHub) tokenSource() (oauth2.TokenSource, error) {
+	if gh.appId == 0 && gh.appInstallationId == 0 {
+		token := os.Getenv("GITHUB_TOKEN")
+		if len(strings.TrimSpace(token)) == 0 {
+			return nil, fmt.Errorf("a GITHUB_TOKEN environment variable needs to be set")
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), nil
+	}
+
+	key := gh.appPrivateKey
+	if len(gh.appPrivateKeyPath) != 0 {
+		content, err := os.ReadFile(gh.appPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the GitHub App private key. error: %v", err)
+		}
+		key = content
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("a GitHub App private key needs to be provided")
+	}
+	return helpers.NewAppTokenSource(gh.context, gh.enterpriseEndpoint, gh.appId, gh.appInstallationId, key)
+}
+
 func (gh *GitHub) ListPRs(ctx context.Context, states []string, owner, repo string) ([]*GitHubPR, error) {
 	if len(owner) == 0 {
 		return nil, fmt.Errorf("an owner must be specified")
@@ -105,19 +162,53 @@ func (gh *GitHub) ListPRs(ctx context.Context, states []string, owner, repo stri
 			PullRequests struct {
 				Nodes []struct {
 					Id      string
+					Number  int
+					Url     string
 					Commits struct {
 						Nodes []struct {
 							Commit struct {
 								CommittedDate time.Time
 							}
-							PullRequest struct {
-								Number int
-								Url    string
-							}
 						}
 					} `graphql:"commits(last: 1)"`
 					BaseRefName string
 					HeadRefName string
+					HeadRefOid  string
+					State       string
+					Author      struct {
+						Login string
+					}
+					IsCrossRepository bool
+					IsDraft           bool
+					ReviewDecision    string
+					Mergeable         string
+					CreatedAt         time.Time
+					UpdatedAt         time.Time
+					Labels            struct {
+						Nodes []struct {
+							Name string
+						}
+					} `graphql:"labels(first: 100)"`
+					Comments struct {
+						Nodes []struct {
+							CreatedAt time.Time
+						}
+					} `graphql:"comments(last: 1)"`
+					Reviews struct {
+						Nodes []struct {
+							SubmittedAt time.Time
+						}
+					} `graphql:"reviews(last: 1)"`
+					TimelineItems struct {
+						Nodes []struct {
+							LabeledEvent struct {
+								CreatedAt time.Time
+								Label     struct {
+									Name string
+								}
+							} `graphql:"... on LabeledEvent"`
+						}
+					} `graphql:"timelineItems(last: 50, itemTypes: [LABELED_EVENT])"`
 				}
 				PageInfo struct {
 					EndCursor   string
@@ -146,14 +237,42 @@ func (gh *GitHub) ListPRs(ctx context.Context, states []string, owner, repo stri
 		}
 
 		for _, pr := range query.Repository.PullRequests.Nodes {
-			out = append(out, &GitHubPR{
-				Source:         pr.HeadRefName,
-				Target:         pr.BaseRefName,
-				LastCommitDate: pr.Commits.Nodes[0].Commit.CommittedDate,
-				Id:             pr.Id,
-				Number:         pr.Commits.Nodes[0].PullRequest.Number,
-				Url:            pr.Commits.Nodes[0].PullRequest.Url,
-			})
+			model := &GitHubPR{
+				Source:          pr.HeadRefName,
+				Target:          pr.BaseRefName,
+				HeadSha:         pr.HeadRefOid,
+				State:           pr.State,
+				Id:              pr.Id,
+				Number:          pr.Number,
+				Url:             pr.Url,
+				Author:          pr.Author.Login,
+				Draft:           pr.IsDraft,
+				CrossRepository: pr.IsCrossRepository,
+				ReviewDecision:  pr.ReviewDecision,
+				Mergeable:       pr.Mergeable,
+				CreatedAt:       pr.CreatedAt,
+				UpdatedAt:       pr.UpdatedAt,
+			}
+			if len(pr.Commits.Nodes) != 0 {
+				model.LastCommitDate = pr.Commits.Nodes[0].Commit.CommittedDate
+			}
+			if len(pr.Reviews.Nodes) != 0 && !pr.Reviews.Nodes[0].SubmittedAt.IsZero() {
+				model.LastReviewDate = &pr.Reviews.Nodes[0].SubmittedAt
+			}
+			for _, label := range pr.Labels.Nodes {
+				model.Labels = append(model.Labels, label.Name)
+			}
+			if len(pr.Comments.Nodes) != 0 {
+				model.LastCommentDate = &pr.Comments.Nodes[0].CreatedAt
+			}
+			for _, item := range pr.TimelineItems.Nodes {
+				if model.LabeledAt == nil {
+					model.LabeledAt = make(map[string]time.Time)
+				}
+				// timeline items are sorted chronologically, so the last event of each label wins
+				model.LabeledAt[item.LabeledEvent.Label.Name] = item.LabeledEvent.CreatedAt
+			}
+			out = append(out, model)
 		}
 		if !query.Repository.PullRequests.PageInfo.HasNextPage {
 			break
@@ -163,14 +282,120 @@ func (gh *GitHub) ListPRs(ctx context.Context, states []string, owner, repo stri
 	return out, nil
 }
 
+// ListRepositories lists every repository owned by the provided organisation or user login.
+func (gh *GitHub) ListRepositories(ctx context.Context, login string) ([]*GitHubRepository, error) {
+	if len(login) == 0 {
+		return nil, fmt.Errorf("an owner must be specified")
+	}
+
+	var query struct {
+		RepositoryOwner struct {
+			Repositories struct {
+				Nodes []struct {
+					Id    string
+					Name  string
+					Owner struct {
+						Login string
+					}
+					IsArchived       bool
+					IsFork           bool
+					Visibility       string
+					RepositoryTopics struct {
+						Nodes []struct {
+							Topic struct {
+								Name string
+							}
+						}
+					} `graphql:"repositoryTopics(first: 20)"`
+				}
+				PageInfo struct {
+					EndCursor   string
+					HasNextPage bool
+				}
+			} `graphql:"repositories(first: $first, after: $after, ownerAffiliations: [OWNER])"`
+		} `graphql:"repositoryOwner(login: $login)"`
+	}
+
+	variables := map[string]interface{}{
+		"login": githubv4.String(login),
+		"first": githubv4.Int(100),
+		"after": (*githubv4.String)(nil),
+	}
+
+	var out []*GitHubRepository
+	for {
+		if err := gh.clientV4.Query(ctx, &query, variables); err != nil {
+			return nil, err
+		}
+
+		for _, n := range query.RepositoryOwner.Repositories.Nodes {
+			model := &GitHubRepository{
+				Id:         n.Id,
+				Owner:      n.Owner.Login,
+				Name:       n.Name,
+				Archived:   n.IsArchived,
+				Fork:       n.IsFork,
+				Visibility: n.Visibility,
+			}
+			for _, t := range n.RepositoryTopics.Nodes {
+				model.Topics = append(model.Topics, t.Topic.Name)
+			}
+			out = append(out, model)
+		}
+
+		if !query.RepositoryOwner.Repositories.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = githubv4.String(query.RepositoryOwner.Repositories.PageInfo.EndCursor)
+	}
+	return out, nil
+}
+
 type RefType = string
 
+type ProtectionReason = string
+
+const (
+	ProtectedByDefaultBranch    ProtectionReason = "default-branch"
+	ProtectedByBranchProtection                  = "branch-protection"
+	ProtectedByRuleset                           = "ruleset"
+)
+
 const (
 	BranchRefType RefType = "refs/heads/"
 	TagRefType            = "refs/tags/"
 )
 
-func (gh *GitHub) ListRefs(ctx context.Context, owner, repo string, refType RefType) ([]*GitHubRef, error) {
+// gitActor is the author or committer of a commit. The user is only set when the actor matches a GitHub account.
+type gitActor struct {
+	Name  string
+	Email string
+	User  struct {
+		Login string
+	}
+}
+
+func (a *gitActor) model() *GitHubActor {
+	if len(a.Name) == 0 && len(a.Email) == 0 && len(a.User.Login) == 0 {
+		return nil
+	}
+	return &GitHubActor{Login: a.User.Login, Name: a.Name, Email: a.Email}
+}
+
+type listRefsOptions struct {
+	associatedPRs bool
+}
+
+type ListRefsOption = func(*listRefsOptions)
+
+// WithAssociatedPRs makes ListRefs fetch the PRs opened from each listed branch.
+func WithAssociatedPRs() ListRefsOption {
+	return func(o *listRefsOptions) {
+		o.associatedPRs = true
+	}
+}
+
+func (gh *GitHub) ListRefs(ctx context.Context, owner, repo string, refType RefType, opts ...ListRefsOption) ([]*GitHubRef, error) {
 	if len(owner) == 0 {
 		return nil, fmt.Errorf("an owner must be specified")
 	}
@@ -181,13 +406,25 @@ func (gh *GitHub) ListRefs(ctx context.Context, owner, repo string, refType RefT
 
 	var query struct {
 		Repository struct {
+			DefaultBranchRef struct {
+				Name string
+			}
 			Refs struct {
 				Nodes []struct {
-					Id     string
-					Name   string
+					Id                   string
+					Name                 string
+					BranchProtectionRule struct {
+						Pattern string
+					}
+					Rules struct {
+						TotalCount int
+					} `graphql:"rules(first: 1)"`
 					Target struct {
+						Oid    string
 						Commit struct {
 							CommittedDate time.Time
+							Author        gitActor
+							Committer     gitActor
 						} `graphql:"... on Commit"`
 						Tag struct {
 							Tagger struct {
@@ -222,7 +459,21 @@ func (gh *GitHub) ListRefs(ctx context.Context, owner, repo string, refType RefT
 			commitDate := n.Target.Commit.CommittedDate
 			tagDate := n.Target.Tag.Tagger.Date
 
-			model := &GitHubRef{Name: n.Name, Id: n.Id}
+			model := &GitHubRef{
+				Name:      n.Name,
+				Id:        n.Id,
+				Sha:       n.Target.Oid,
+				Author:    n.Target.Commit.Author.model(),
+				Committer: n.Target.Commit.Committer.model(),
+			}
+			switch {
+			case refType == BranchRefType && n.Name == query.Repository.DefaultBranchRef.Name:
+				model.Protected, model.ProtectedBy = true, ProtectedByDefaultBranch
+			case len(n.BranchProtectionRule.Pattern) != 0:
+				model.Protected, model.ProtectedBy = true, ProtectedByBranchProtection
+			case n.Rules.TotalCount != 0:
+				model.Protected, model.ProtectedBy = true, ProtectedByRuleset
+			}
 			if !commitDate.IsZero() {
 				model.LastCommitDate = &commitDate
 			}
@@ -237,6 +488,408 @@ func (gh *GitHub) ListRefs(ctx context.Context, owner, repo string, refType RefT
 		}
 		variables["after"] = githubv4.String(query.Repository.Refs.PageInfo.EndCursor)
 	}
+
+	options := new(listRefsOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.associatedPRs && refType == BranchRefType {
+		if err := gh.AssociatePRs(ctx, out...); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ListReleaseTags lists the tag names of every GitHub Release of the provided repository.
+func (gh *GitHub) ListReleaseTags(ctx context.Context, owner, repo string) ([]string, error) {
+	if len(owner) == 0 {
+		return nil, fmt.Errorf("an owner must be specified")
+	}
+
+	if len(repo) == 0 {
+		return nil, fmt.Errorf("a repo must be specified")
+	}
+
+	var query struct {
+		Repository struct {
+			Releases struct {
+				Nodes []struct {
+					TagName string
+				}
+				PageInfo struct {
+					EndCursor   string
+					HasNextPage bool
+				}
+			} `graphql:"releases(first: $first, after: $after)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+		"first": githubv4.Int(100),
+		"after": (*githubv4.String)(nil),
+	}
+
+	var out []string
+	for {
+		if err := gh.clientV4.Query(ctx, &query, variables); err != nil {
+			return nil, err
+		}
+
+		for _, n := range query.Repository.Releases.Nodes {
+			out = append(out, n.TagName)
+		}
+
+		if !query.Repository.Releases.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = githubv4.String(query.Repository.Releases.PageInfo.EndCursor)
+	}
+	return out, nil
+}
+
+func (gh *GitHub) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	if len(owner) == 0 {
+		return "", fmt.Errorf("an owner must be specified")
+	}
+
+	if len(repo) == 0 {
+		return "", fmt.Errorf("a repo must be specified")
+	}
+
+	var query struct {
+		Repository struct {
+			DefaultBranchRef struct {
+				Name string
+			}
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := gh.clientV4.Query(ctx, &query, variables); err != nil {
+		return "", err
+	}
+	return query.Repository.DefaultBranchRef.Name, nil
+}
+
+// CompareRefs compares each of the provided refs against the base ref, marking them as merged when their HEAD is
+// reachable from it and counting the commits they are ahead & behind of it. Refs are compared in batches of
+// _compareBatchSize. Refs that cannot be compared, e.g. because the base ref does not exist, are left untouched.
+func (gh *GitHub) CompareRefs(ctx context.Context, base string, refs ...*GitHubRef) error {
+	if len(base) == 0 {
+		return fmt.Errorf("a base ref must be specified")
+	}
+
+	var query struct {
+		Nodes []struct {
+			Ref struct {
+				Id string
+				// null when the base ref cannot be resolved
+				Compare *struct {
+					Status   githubv4.ComparisonStatus
+					AheadBy  int
+					BehindBy int
+				} `graphql:"compare(headRef: $base)"`
+			} `graphql:"... on Ref"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	byId := make(map[string]*GitHubRef, len(refs))
+	for _, ref := range refs {
+		byId[ref.Id] = ref
+	}
+
+	for start := 0; start < len(refs); start += _compareBatchSize {
+		end := start + _compareBatchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+
+		var ids []githubv4.ID
+		for _, ref := range refs[start:end] {
+			ids = append(ids, githubv4.ID(ref.Id))
+		}
+		variables := map[string]interface{}{
+			"ids":  ids,
+			"base": githubv4.String(base),
+		}
+		query.Nodes = nil
+		if err := gh.clientV4.Query(ctx, &query, variables); err != nil {
+			return fmt.Errorf("unable to compare refs against: %v. error: %v", base, err)
+		}
+
+		for _, n := range query.Nodes {
+			ref, found := byId[n.Ref.Id]
+			if !found || n.Ref.Compare == nil {
+				continue
+			}
+			// the base ref is used as the comparison head, so the ref is merged when the base is ahead or identical
+			merged := n.Ref.Compare.Status == githubv4.ComparisonStatusAhead ||
+				n.Ref.Compare.Status == githubv4.ComparisonStatusIdentical
+			ref.Merged = &merged
+			// likewise, the commits the base is ahead by are the ones the ref is behind by and vice versa
+			ahead, behind := n.Ref.Compare.BehindBy, n.Ref.Compare.AheadBy
+			ref.AheadBy, ref.BehindBy = &ahead, &behind
+		}
+	}
+	return nil
+}
+
+// AssociatePRs fetches the most recent PRs opened from each of the provided refs, newest first. Refs are queried in
+// batches of _nodesBatchSize.
+func (gh *GitHub) AssociatePRs(ctx context.Context, refs ...*GitHubRef) error {
+	var query struct {
+		Nodes []struct {
+			Ref struct {
+				Id                     string
+				AssociatedPullRequests struct {
+					Nodes []struct {
+						Id     string
+						Number int
+						State  string
+						Url    string
+						Author struct {
+							Login string
+						}
+						IsCrossRepository bool
+						CreatedAt         time.Time
+					}
+				} `graphql:"associatedPullRequests(first: 5, orderBy: {field: CREATED_AT, direction: DESC})"`
+			} `graphql:"... on Ref"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	byId := make(map[string]*GitHubRef, len(refs))
+	var ids []string
+	for _, ref := range refs {
+		byId[ref.Id] = ref
+		ids = append(ids, ref.Id)
+	}
+
+	for _, batch := range batches(ids, _nodesBatchSize) {
+		if err := gh.clientV4.Query(ctx, &query, map[string]interface{}{"ids": batch}); err != nil {
+			return fmt.Errorf("unable to fetch the PRs associated with refs. error: %v", err)
+		}
+
+		for _, n := range query.Nodes {
+			ref, found := byId[n.Ref.Id]
+			if !found {
+				continue
+			}
+			ref.PullRequests = nil
+			for _, pr := range n.Ref.AssociatedPullRequests.Nodes {
+				ref.PullRequests = append(ref.PullRequests, &GitHubRefPR{
+					Id:                pr.Id,
+					Number:            pr.Number,
+					State:             pr.State,
+					Url:               pr.Url,
+					Author:            pr.Author.Login,
+					IsCrossRepository: pr.IsCrossRepository,
+					CreatedAt:         pr.CreatedAt,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (gh *GitHub) RepositoryId(ctx context.Context, owner, repo string) (string, error) {
+	if len(owner) == 0 {
+		return "", fmt.Errorf("an owner must be specified")
+	}
+
+	if len(repo) == 0 {
+		return "", fmt.Errorf("a repo must be specified")
+	}
+
+	var query struct {
+		Repository struct {
+			Id string
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := gh.clientV4.Query(ctx, &query, variables); err != nil {
+		return "", err
+	}
+	return query.Repository.Id, nil
+}
+
+// CreateRef creates the fully qualified ref name (e.g. refs/heads/main) pointing at the provided object id.
+func (gh *GitHub) CreateRef(ctx context.Context, repositoryId, name, oid string) error {
+	if len(repositoryId) == 0 {
+		return fmt.Errorf("a repository id must be specified")
+	}
+	if len(name) == 0 || len(oid) == 0 {
+		return fmt.Errorf("both a ref name and a target oid must be specified")
+	}
+
+	var mutation struct {
+		CreateRef struct {
+			Ref struct {
+				Id         string
+				Repository struct{ NameWithOwner string }
+			}
+		} `graphql:"createRef(input: $input)"`
+	}
+
+	input := githubv4.CreateRefInput{
+		RepositoryID: githubv4.ID(repositoryId),
+		Name:         githubv4.String(name),
+		Oid:          githubv4.GitObjectID(oid),
+	}
+	err := gh.clientV4.Mutate(ctx, &mutation, input, nil)
+	if err != nil {
+		err = fmt.Errorf("unable to create ref: %v. error: %v", name, err)
+	}
+
+	// the id & repository of the ref are only known once it has been created
+	entry := &AuditEntry{Repository: mutation.CreateRef.Ref.Repository.NameWithOwner, Type: BranchManifestEntry,
+		Id: mutation.CreateRef.Ref.Id, Name: strings.TrimPrefix(name, "refs/heads/"), Sha: oid}
+	if strings.HasPrefix(name, "refs/tags/") {
+		entry.Type, entry.Name = TagManifestEntry, strings.TrimPrefix(name, "refs/tags/")
+	}
+	gh.audit(ctx, CreateRefAuditAction, []*AuditEntry{entry}, []error{err})
+	return err
+}
+
+// GetRefs fetches the current state of the refs with the provided node ids. Refs that no longer exist are omitted.
+func (gh *GitHub) GetRefs(ctx context.Context, ids ...string) (map[string]*GitHubRef, error) {
+	var query struct {
+		Nodes []struct {
+			Ref struct {
+				Id     string
+				Name   string
+				Target struct {
+					Oid    string
+					Commit struct {
+						CommittedDate time.Time
+					} `graphql:"... on Commit"`
+					Tag struct {
+						Tagger struct {
+							Date time.Time
+						}
+					} `graphql:"... on Tag"`
+				}
+			} `graphql:"... on Ref"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	out := make(map[string]*GitHubRef, len(ids))
+	for _, b := range batches(ids, _nodesBatchSize) {
+		if err := gh.clientV4.Query(ctx, &query, map[string]interface{}{"ids": b}); err != nil {
+			return nil, err
+		}
+		for _, n := range query.Nodes {
+			if len(n.Ref.Id) == 0 {
+				continue
+			}
+			commitDate := n.Ref.Target.Commit.CommittedDate
+			tagDate := n.Ref.Target.Tag.Tagger.Date
+
+			model := &GitHubRef{Name: n.Ref.Name, Id: n.Ref.Id, Sha: n.Ref.Target.Oid}
+			if !commitDate.IsZero() {
+				model.LastCommitDate = &commitDate
+			}
+			if !tagDate.IsZero() {
+				model.TagDate = &tagDate
+			}
+			out[model.Id] = model
+		}
+	}
+	return out, nil
+}
+
+// GetPRs fetches the current state of the PRs with the provided node ids. PRs that no longer exist are omitted.
+func (gh *GitHub) GetPRs(ctx context.Context, ids ...string) (map[string]*GitHubPR, error) {
+	var query struct {
+		Nodes []struct {
+			PullRequest struct {
+				Id      string
+				Number  int
+				Url     string
+				Commits struct {
+					Nodes []struct {
+						Commit struct {
+							CommittedDate time.Time
+						}
+					}
+				} `graphql:"commits(last: 1)"`
+				BaseRefName string
+				HeadRefName string
+				HeadRefOid  string
+				State       string
+			} `graphql:"... on PullRequest"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	out := make(map[string]*GitHubPR, len(ids))
+	for _, b := range batches(ids, _nodesBatchSize) {
+		if err := gh.clientV4.Query(ctx, &query, map[string]interface{}{"ids": b}); err != nil {
+			return nil, err
+		}
+		for _, n := range query.Nodes {
+			pr := n.PullRequest
+			if len(pr.Id) == 0 {
+				continue
+			}
+			model := &GitHubPR{
+				Source:  pr.HeadRefName,
+				Target:  pr.BaseRefName,
+				HeadSha: pr.HeadRefOid,
+				State:   pr.State,
+				Id:      pr.Id,
+				Number:  pr.Number,
+				Url:     pr.Url,
+			}
+			if len(pr.Commits.Nodes) != 0 {
+				model.LastCommitDate = pr.Commits.Nodes[0].Commit.CommittedDate
+			}
+			out[model.Id] = model
+		}
+	}
+	return out, nil
+}
+
+// ViewerComments fetches the bodies of the comments the session (viewer) posted among the last 100 comments of each of
+// the PRs with the provided node ids, oldest first. PRs without such comments are omitted.
+func (gh *GitHub) ViewerComments(ctx context.Context, ids ...string) (map[string][]string, error) {
+	var query struct {
+		Nodes []struct {
+			PullRequest struct {
+				Id       string
+				Comments struct {
+					Nodes []struct {
+						Body            string
+						ViewerDidAuthor bool
+					}
+				} `graphql:"comments(last: 100)"`
+			} `graphql:"... on PullRequest"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	out := make(map[string][]string)
+	for _, b := range batches(ids, _nodesBatchSize) {
+		query.Nodes = nil
+		if err := gh.clientV4.Query(ctx, &query, map[string]interface{}{"ids": b}); err != nil {
+			return nil, fmt.Errorf("unable to list the PR comments. error: %v", err)
+		}
+		for _, n := range query.Nodes {
+			for _, comment := range n.PullRequest.Comments.Nodes {
+				if comment.ViewerDidAuthor {
+					out[n.PullRequest.Id] = append(out[n.PullRequest.Id], comment.Body)
+				}
+			}
+		}
+	}
 	return out, nil
 }
 
@@ -262,22 +915,26 @@ func (gh *GitHub) DeleteRefs(ctx context.Context, refs ...string) error {
 		close(sem)
 	}()
 
-	for _, ref := range refs {
+	entries := gh.describe(ctx, refs...)
+	results := make([]error, len(refs))
+	for i, ref := range refs {
 		sem <- struct{}{}
-		go func(r string) {
+		go func(i int, r string) {
 			reqErr := gh.clientV4.Mutate(ctx, &mutation, githubv4.Input(r), nil)
 			if reqErr != nil {
+				results[i] = reqErr
 				ec <- fmt.Errorf("unable to delete ref: %v. error: %v", r, reqErr)
 			}
 			wg.Done()
 			<-sem
-		}(ref)
+		}(i, ref)
 	}
 
 	var err error
 	for e := range ec {
 		err = errors.Join(err, e)
 	}
+	gh.audit(ctx, DeleteRefAuditAction, entries, results)
 	return err
 }
 
@@ -303,16 +960,65 @@ func (gh *GitHub) ClosePRs(ctx context.Context, ids ...string) error {
 		close(sem)
 	}()
 
-	for _, id := range ids {
+	entries := gh.describe(ctx, ids...)
+	results := make([]error, len(ids))
+	for i, id := range ids {
 		sem <- struct{}{}
-		go func(identifier string) {
+		go func(i int, identifier string) {
 			reqErr := gh.clientV4.Mutate(ctx, &mutation, githubv4.Input(identifier), nil)
 			if reqErr != nil {
+				results[i] = reqErr
 				ec <- fmt.Errorf("unable to close PR: %v. error: %v", identifier, reqErr)
 			}
 			wg.Done()
 			<-sem
-		}(id)
+		}(i, id)
+	}
+
+	var err error
+	for e := range ec {
+		err = errors.Join(err, e)
+	}
+
+	gh.audit(ctx, ClosePRAuditAction, entries, results)
+	return err
+}
+
+func (gh *GitHub) ReopenPRs(ctx context.Context, ids ...string) error {
+	if ids == nil || len(ids) == 0 {
+		return fmt.Errorf("no PR ids have been specified")
+	}
+
+	var mutation struct {
+		ReopenPullRequest struct {
+			Typename string `graphql:"typename :__typename"`
+		} `graphql:"reopenPullRequest(input: {pullRequestId: $input})"`
+	}
+
+	ec := make(chan error, len(ids))
+	sem := make(chan struct{}, gh.workerCount)
+
+	var wg sync.WaitGroup
+	wg.Add(len(ids))
+	go func() {
+		wg.Wait()
+		close(ec)
+		close(sem)
+	}()
+
+	entries := gh.describe(ctx, ids...)
+	results := make([]error, len(ids))
+	for i, id := range ids {
+		sem <- struct{}{}
+		go func(i int, identifier string) {
+			reqErr := gh.clientV4.Mutate(ctx, &mutation, githubv4.Input(identifier), nil)
+			if reqErr != nil {
+				results[i] = reqErr
+				ec <- fmt.Errorf("unable to reopen PR: %v. error: %v", identifier, reqErr)
+			}
+			wg.Done()
+			<-sem
+		}(i, id)
 	}
 
 	var err error
@@ -320,5 +1026,76 @@ func (gh *GitHub) ClosePRs(ctx context.Context, ids ...string) error {
 		err = errors.Join(err, e)
 	}
 
+	gh.audit(ctx, ReopenPRAuditAction, entries, results)
+	return err
+}
+
+func batches(ids []string, size int) [][]githubv4.ID {
+	var out [][]githubv4.ID
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var b []githubv4.ID
+		for _, id := range ids[start:end] {
+			b = append(b, githubv4.ID(id))
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (gh *GitHub) AddComment(ctx context.Context, subjectId, body string) error {
+	if len(subjectId) == 0 {
+		return fmt.Errorf("a subject id must be specified")
+	}
+	if len(strings.TrimSpace(body)) == 0 {
+		return fmt.Errorf("a comment body must be specified")
+	}
+
+	var mutation struct {
+		AddComment struct {
+			Typename string `graphql:"typename :__typename"`
+		} `graphql:"addComment(input: $input)"`
+	}
+
+	input := githubv4.AddCommentInput{
+		SubjectID: githubv4.ID(subjectId),
+		Body:      githubv4.String(body),
+	}
+	entries := gh.describe(ctx, subjectId)
+	err := gh.clientV4.Mutate(ctx, &mutation, input, nil)
+	if err != nil {
+		err = fmt.Errorf("unable to comment on: %v. error: %v", subjectId, err)
+	}
+	gh.audit(ctx, CommentAuditAction, entries, []error{err})
+	return err
+}
+
+// AddLabels adds the labels to the issue or PR with the provided number. Missing labels are created by GitHub.
+func (gh *GitHub) AddLabels(ctx context.Context, owner, repo string, number int, labels ...string) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("no labels have been specified")
+	}
+	_, _, err := gh.clientV3.Issues.AddLabelsToIssue(ctx, owner, repo, number, labels)
+	if err != nil {
+		err = fmt.Errorf("unable to label: %v/%v#%d. error: %v", owner, repo, number, err)
+	}
+	entry := &AuditEntry{Repository: owner + "/" + repo, Type: PRManifestEntry, Number: number, Labels: labels}
+	gh.audit(ctx, AddLabelsAuditAction, []*AuditEntry{entry}, []error{err})
+	return err
+}
+
+func (gh *GitHub) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	if len(label) == 0 {
+		return fmt.Errorf("a label must be specified")
+	}
+	_, err := gh.clientV3.Issues.RemoveLabelForIssue(ctx, owner, repo, number, label)
+	if err != nil {
+		err = fmt.Errorf("unable to remove label: %v from: %v/%v#%d. error: %v", label, owner, repo, number, err)
+	}
+	entry := &AuditEntry{Repository: owner + "/" + repo, Type: PRManifestEntry, Number: number, Labels: []string{label}}
+	gh.audit(ctx, RemoveLabelAuditAction, []*AuditEntry{entry}, []error{err})
 	return err
 }
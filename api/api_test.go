@@ -2,6 +2,10 @@ package api_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"github.com/pcanilho/gh-tidy/api"
 	"github.com/stretchr/testify/assert"
@@ -9,7 +13,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -41,6 +47,58 @@ func TestNewSession(t *testing.T) {
 	})
 }
 
+func TestNewSession_GitHubApp(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Unsetenv(envKey))
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var issued int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations/2/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "))
+		assert.Len(t, strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), "."), 3)
+		issued++
+		// the token expires within the early expiry window so that every request triggers a refresh
+		writeBody(t, w, fmt.Sprintf(`{"token":"ghs_%d","expires_at":"%v"}`, issued, time.Now().Add(time.Minute).Format(time.RFC3339)))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, fmt.Sprintf("Bearer ghs_%d", issued), r.Header.Get("Authorization"))
+		writeBody(t, w, `{"data": {"repository": {"refs": {"nodes": []}}}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("session-app-invalid-key", func(ti *testing.T) {
+		s, err := api.NewSession(api.WithGitHubApp(1, 2, []byte("invalid")))
+		assert.Error(ti, err)
+		assert.Nil(ti, s)
+	})
+	t.Run("session-app-missing-installation", func(ti *testing.T) {
+		s, err := api.NewSession(api.WithGitHubApp(1, 0, privateKey))
+		assert.Error(ti, err)
+		assert.Nil(ti, s)
+	})
+	t.Run("session-app-token-refresh", func(ti *testing.T) {
+		s, err := api.NewSession(
+			api.WithEnterpriseEndpoint(server.URL+"/"),
+			api.WithGitHubApp(1, 2, privateKey))
+		assert.NoError(ti, err)
+		assert.NotNil(ti, s)
+
+		for i := 1; i <= 2; i++ {
+			_, err = s.ListRefs(context.Background(), "x", "y", api.BranchRefType)
+			assert.NoError(ti, err)
+			assert.Equal(ti, i, issued)
+		}
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
 func TestGitHub_ListRefs(t *testing.T) {
 	envKey := "GITHUB_TOKEN"
 	old := os.Getenv(envKey)
@@ -58,8 +116,8 @@ func TestGitHub_ListRefs(t *testing.T) {
 		assert.Equal(t, http.MethodPost, r.Method)
 		assert.Equal(t,
 			readBody(t, r),
-			fmt.Sprintf(`{"query":"query($after:String$first:Int!$name:String!$owner:String!$refPrefix:String!){repository(owner: $owner, name: $name){refs(first: $first, after: $after, refPrefix: $refPrefix){nodes{id,name,target{... on Commit{committedDate},... on Tag{tagger{date}}}},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"name":"%v","owner":"%v","refPrefix":"%v"}}`, repo, owner, *refType))
-		writeBody(t, w, fmt.Sprintf(`{"data": {"repository": {"refs": {"nodes": [{"id": "007", "name": "test-ref", "target": {"committedDate": "%v", "tagger": {"date": "%v"}}}]}}}}`, t0, t0))
+			fmt.Sprintf(`{"query":"query($after:String$first:Int!$name:String!$owner:String!$refPrefix:String!){repository(owner: $owner, name: $name){defaultBranchRef{name},refs(first: $first, after: $after, refPrefix: $refPrefix){nodes{id,name,branchProtectionRule{pattern},rules(first: 1){totalCount},target{oid,... on Commit{committedDate,author{name,email,user{login}},committer{name,email,user{login}}},... on Tag{tagger{date}}}},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"name":"%v","owner":"%v","refPrefix":"%v"}}`, repo, owner, *refType))
+		writeBody(t, w, fmt.Sprintf(`{"data": {"repository": {"refs": {"nodes": [{"id": "007", "name": "test-ref", "target": {"oid": "abc", "committedDate": "%v", "author": {"name": "Mona", "email": "mona@x.y", "user": {"login": "octocat"}}, "committer": {"name": "GitHub", "email": "noreply@github.com", "user": null}, "tagger": {"date": "%v"}}}]}}}}`, t0, t0))
 	})
 	{
 		*refType = api.BranchRefType
@@ -70,8 +128,11 @@ func TestGitHub_ListRefs(t *testing.T) {
 			expected := &api.GitHubRef{
 				Id:             "007",
 				Name:           "test-ref",
+				Sha:            "abc",
 				LastCommitDate: &t0p,
 				TagDate:        &t0p,
+				Author:         &api.GitHubActor{Login: "octocat", Name: "Mona", Email: "mona@x.y"},
+				Committer:      &api.GitHubActor{Name: "GitHub", Email: "noreply@github.com"},
 			}
 			assert.Equal(ti, expected, brs[0])
 		})
@@ -82,8 +143,11 @@ func TestGitHub_ListRefs(t *testing.T) {
 			expected := &api.GitHubRef{
 				Id:             "006",
 				Name:           "test-ref",
+				Sha:            "abc",
 				LastCommitDate: &t0p,
 				TagDate:        &t0p,
+				Author:         &api.GitHubActor{Login: "octocat", Name: "Mona", Email: "mona@x.y"},
+				Committer:      &api.GitHubActor{Name: "GitHub", Email: "noreply@github.com"},
 			}
 			assert.NotEqual(ti, expected, brs[0])
 		})
@@ -95,8 +159,11 @@ func TestGitHub_ListRefs(t *testing.T) {
 			expected := &api.GitHubRef{
 				Id:             "007",
 				Name:           "test-ref",
+				Sha:            "abc",
 				LastCommitDate: &t0p,
 				TagDate:        &t0p,
+				Author:         &api.GitHubActor{Login: "octocat", Name: "Mona", Email: "mona@x.y"},
+				Committer:      &api.GitHubActor{Name: "GitHub", Email: "noreply@github.com"},
 			}
 			assert.Equal(ti, expected, brs[0])
 		})
@@ -117,6 +184,71 @@ func TestGitHub_ListRefs(t *testing.T) {
 	assert.NoError(t, os.Setenv(envKey, old))
 }
 
+func TestGitHub_ListRefs_Protected(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		writeBody(t, w, `{"data": {"repository": {"defaultBranchRef": {"name": "main"}, "refs": {"nodes": [
+			{"id": "1", "name": "main"},
+			{"id": "2", "name": "release/1.0", "branchProtectionRule": {"pattern": "release/*"}},
+			{"id": "3", "name": "locked", "rules": {"totalCount": 1}},
+			{"id": "4", "name": "feature"}]}}}}`)
+	})
+	t.Run("list-refs-branches-protected", func(ti *testing.T) {
+		brs, err := ghApi.ListRefs(context.Background(), "x", "y", api.BranchRefType)
+		assert.NoError(ti, err)
+		assert.Len(ti, brs, 4)
+		for i, expected := range []string{api.ProtectedByDefaultBranch, api.ProtectedByBranchProtection, api.ProtectedByRuleset, ""} {
+			assert.Equal(ti, len(expected) != 0, brs[i].Protected)
+			assert.Equal(ti, expected, brs[i].ProtectedBy)
+		}
+	})
+	t.Run("list-refs-tags-default-branch-name", func(ti *testing.T) {
+		tgs, err := ghApi.ListRefs(context.Background(), "x", "y", api.TagRefType)
+		assert.NoError(ti, err)
+		assert.False(ti, tgs[0].Protected)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_ListRefs_WithAssociatedPRs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	var queries int
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		queries++
+		if strings.Contains(readBody(t, r), "associatedPullRequests") {
+			writeBody(t, w, `{"data":{"nodes":[{"id":"1","associatedPullRequests":{"nodes":[{"id":"p","number":7,"state":"OPEN","url":"u"}]}}]}}`)
+			return
+		}
+		writeBody(t, w, `{"data": {"repository": {"refs": {"nodes": [{"id": "1", "name": "feature"}]}}}}`)
+	})
+	t.Run("list-refs-branches-w/-prs", func(ti *testing.T) {
+		queries = 0
+		brs, err := ghApi.ListRefs(context.Background(), "x", "y", api.BranchRefType, api.WithAssociatedPRs())
+		assert.NoError(ti, err)
+		assert.Equal(ti, 2, queries)
+		assert.Equal(ti, []*api.GitHubRefPR{{Id: "p", Number: 7, State: "OPEN", Url: "u"}}, brs[0].PullRequests)
+		assert.True(ti, brs[0].HasOpenPR())
+	})
+	t.Run("list-refs-tags-w/-prs", func(ti *testing.T) {
+		queries = 0
+		tgs, err := ghApi.ListRefs(context.Background(), "x", "y", api.TagRefType, api.WithAssociatedPRs())
+		assert.NoError(ti, err)
+		assert.Equal(ti, 1, queries)
+		assert.Nil(ti, tgs[0].PullRequests)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
 func TestGitHub_ListPRs(t *testing.T) {
 	envKey := "GITHUB_TOKEN"
 	old := os.Getenv(envKey)
@@ -134,8 +266,8 @@ func TestGitHub_ListPRs(t *testing.T) {
 		assert.Equal(t, http.MethodPost, r.Method)
 		assert.Equal(t,
 			readBody(t, r),
-			fmt.Sprintf(`{"query":"query($after:String$first:Int!$name:String!$owner:String!$states:[PullRequestState!]!){repository(owner: $owner, name: $name){pullRequests(first: $first, after: $after, states: $states){nodes{id,commits(last: 1){nodes{commit{committedDate},pullRequest{number,url}}},baseRefName,headRefName},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"name":"%v","owner":"%v","states":["OPEN"]}}`, repo, owner))
-		writeBody(t, w, fmt.Sprintf(`{"data":{"repository":{"pullRequests":{"nodes":[{"id":"007","commits":{"nodes":[{"commit":{"committedDate":"%v"},"pullRequest":{"number":7,"url":"%v"}}]},"baseRefName":"%v","headRefName":"%v"}]}}}}`, t0, url, baseName, headName))
+			fmt.Sprintf(`{"query":"query($after:String$first:Int!$name:String!$owner:String!$states:[PullRequestState!]!){repository(owner: $owner, name: $name){pullRequests(first: $first, after: $after, states: $states){nodes{id,number,url,commits(last: 1){nodes{commit{committedDate}}},baseRefName,headRefName,headRefOid,state,author{login},isCrossRepository,isDraft,reviewDecision,mergeable,createdAt,updatedAt,labels(first: 100){nodes{name}},comments(last: 1){nodes{createdAt}},reviews(last: 1){nodes{submittedAt}},timelineItems(last: 50, itemTypes: [LABELED_EVENT]){nodes{... on LabeledEvent{createdAt,label{name}}}}},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"name":"%v","owner":"%v","states":["OPEN"]}}`, repo, owner))
+		writeBody(t, w, fmt.Sprintf(`{"data":{"repository":{"pullRequests":{"nodes":[{"id":"007","number":7,"url":"%v","commits":{"nodes":[{"commit":{"committedDate":"%v"}}]},"baseRefName":"%v","headRefName":"%v","headRefOid":"abc","state":"OPEN","author":{"login":"octocat"},"isDraft":true,"reviewDecision":"APPROVED","mergeable":"MERGEABLE","createdAt":"%v","updatedAt":"%v","labels":{"nodes":[{"name":"stale"}]},"comments":{"nodes":[{"createdAt":"%v"}]},"timelineItems":{"nodes":[{"createdAt":"%v","label":{"name":"stale"}}]}}]}}}}`, url, t0, baseName, headName, t0, t0, t0, t0))
 	})
 	{
 		t.Run("list-prs-valid-match", func(ti *testing.T) {
@@ -144,12 +276,23 @@ func TestGitHub_ListPRs(t *testing.T) {
 			assert.Len(ti, prs, 1)
 
 			expected := &api.GitHubPR{
-				Id:             "007",
-				Source:         headName,
-				Target:         baseName,
-				LastCommitDate: t0p,
-				Number:         7,
-				Url:            url,
+				Id:              "007",
+				Source:          headName,
+				Target:          baseName,
+				HeadSha:         "abc",
+				State:           "OPEN",
+				LastCommitDate:  t0p,
+				Number:          7,
+				Url:             url,
+				Author:          "octocat",
+				Draft:           true,
+				ReviewDecision:  "APPROVED",
+				Mergeable:       "MERGEABLE",
+				CreatedAt:       t0p,
+				UpdatedAt:       t0p,
+				Labels:          []string{"stale"},
+				LastCommentDate: &t0p,
+				LabeledAt:       map[string]time.Time{"stale": t0p},
 			}
 			assert.Equal(ti, expected, prs[0])
 		})
@@ -162,6 +305,8 @@ func TestGitHub_ListPRs(t *testing.T) {
 				Id:             "006",
 				Source:         headName,
 				Target:         baseName,
+				HeadSha:        "abc",
+				State:          "OPEN",
 				LastCommitDate: t0p,
 				Number:         7,
 				Url:            url,
@@ -185,6 +330,326 @@ func TestGitHub_ListPRs(t *testing.T) {
 	assert.NoError(t, os.Setenv(envKey, old))
 }
 
+func TestGitHub_ListPRs_WithoutCommits(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		writeBody(t, w, `{"data":{"repository":{"pullRequests":{"nodes":[{"id":"007","number":7,"url":"u","commits":{"nodes":[]},"reviews":{"nodes":[]}}]}}}}`)
+	})
+	t.Run("list-prs-w/o-commits", func(ti *testing.T) {
+		prs, err := ghApi.ListPRs(context.Background(), []string{"OPEN"}, "x", "y")
+		assert.NoError(ti, err)
+		assert.Len(ti, prs, 1)
+		assert.Equal(ti, 7, prs[0].Number)
+		assert.True(ti, prs[0].LastCommitDate.IsZero())
+		assert.Nil(ti, prs[0].LastReviewDate)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHubPR_LastActivity(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	commit, updated, comment, review := created.AddDate(0, 1, 0), created.AddDate(0, 4, 0), created.AddDate(0, 2, 0), created.AddDate(0, 3, 0)
+	pr := &api.GitHubPR{CreatedAt: created, LastCommitDate: commit, UpdatedAt: updated, LastCommentDate: &comment, LastReviewDate: &review}
+
+	for source, expected := range map[api.ActivitySource]time.Time{
+		api.CommitActivitySource:  commit,
+		api.UpdatedActivitySource: updated,
+		api.CommentActivitySource: comment,
+		api.ReviewActivitySource:  review,
+		api.MaxActivitySource:     updated,
+	} {
+		assert.Equal(t, expected, pr.LastActivity(source), source)
+	}
+
+	empty := &api.GitHubPR{CreatedAt: created}
+	for _, source := range []api.ActivitySource{api.CommitActivitySource, api.CommentActivitySource, api.ReviewActivitySource, api.MaxActivitySource} {
+		assert.Equal(t, created, empty.LastActivity(source), source)
+	}
+}
+
+func TestGitHubPR_LabeledSince(t *testing.T) {
+	labeled := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pr := &api.GitHubPR{Labels: []string{"Stale"}, LabeledAt: map[string]time.Time{"Stale": labeled}}
+	assert.Equal(t, labeled, pr.LabeledSince("stale"))
+
+	// the labelling event is out of the fetched timeline
+	before := time.Now()
+	unknown := &api.GitHubPR{Labels: []string{"stale"}}
+	assert.False(t, unknown.LabeledSince("stale").Before(before))
+}
+
+func TestGitHub_DefaultBranch(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	owner, repo := "x", "y"
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			fmt.Sprintf(`{"query":"query($name:String!$owner:String!){repository(owner: $owner, name: $name){defaultBranchRef{name}}}","variables":{"name":"%v","owner":"%v"}}`, repo, owner))
+		writeBody(t, w, `{"data":{"repository":{"defaultBranchRef":{"name":"main"}}}}`)
+	})
+	{
+		t.Run("default-branch-valid", func(ti *testing.T) {
+			name, err := ghApi.DefaultBranch(context.Background(), owner, repo)
+			assert.NoError(ti, err)
+			assert.Equal(ti, "main", name)
+		})
+		t.Run("default-branch-invalid-owner", func(ti *testing.T) {
+			_, err := ghApi.DefaultBranch(context.Background(), "", repo)
+			assert.Error(ti, err)
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_CompareRefs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	base := "main"
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			fmt.Sprintf(`{"query":"query($base:String!$ids:[ID!]!){nodes(ids: $ids){... on Ref{id,compare(headRef: $base){status,aheadBy,behindBy}}}}","variables":{"base":"%v","ids":["a","b","c"]}}`, base))
+		writeBody(t, w, `{"data":{"nodes":[{"id":"a","compare":{"status":"AHEAD","aheadBy":3}},{"id":"b","compare":{"status":"DIVERGED","aheadBy":500,"behindBy":2}},{"id":"c","compare":{"status":"IDENTICAL"}}]}}`)
+	})
+	{
+		t.Run("compare-refs-valid", func(ti *testing.T) {
+			refs := []*api.GitHubRef{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+			assert.NoError(ti, ghApi.CompareRefs(context.Background(), base, refs...))
+			for i, expected := range []bool{true, false, true} {
+				assert.NotNil(ti, refs[i].Merged)
+				assert.Equal(ti, expected, *refs[i].Merged)
+			}
+			for i, expected := range [][2]int{{0, 3}, {2, 500}, {0, 0}} {
+				assert.Equal(ti, expected[0], *refs[i].AheadBy)
+				assert.Equal(ti, expected[1], *refs[i].BehindBy)
+			}
+		})
+		t.Run("compare-refs-invalid-base", func(ti *testing.T) {
+			assert.Error(ti, ghApi.CompareRefs(context.Background(), "", &api.GitHubRef{Id: "a"}))
+		})
+	}
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		writeBody(t, w, `{"data":{"nodes":[{"id":"a","compare":null}]}}`)
+	})
+	t.Run("compare-refs-missing-base", func(ti *testing.T) {
+		ref := &api.GitHubRef{Id: "a"}
+		assert.NoError(ti, ghApi.CompareRefs(context.Background(), "missing", ref))
+		assert.Nil(ti, ref.Merged)
+		assert.Nil(ti, ref.AheadBy)
+		assert.Nil(ti, ref.BehindBy)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_GetRefs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	t0 := "2023-08-29T19:20:49+01:00"
+	t0p, terr := time.Parse(time.RFC3339, t0)
+	assert.NoError(t, terr)
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"query($ids:[ID!]!){nodes(ids: $ids){... on Ref{id,name,target{oid,... on Commit{committedDate},... on Tag{tagger{date}}}}}}","variables":{"ids":["a","b"]}}`)
+		writeBody(t, w, fmt.Sprintf(`{"data":{"nodes":[{"id":"a","name":"feature","target":{"oid":"abc","committedDate":"%v"}},null]}}`, t0))
+	})
+	t.Run("get-refs-valid", func(ti *testing.T) {
+		rfs, err := ghApi.GetRefs(context.Background(), "a", "b")
+		assert.NoError(ti, err)
+		assert.Len(ti, rfs, 1)
+		assert.Equal(ti, &api.GitHubRef{Id: "a", Name: "feature", Sha: "abc", LastCommitDate: &t0p}, rfs["a"])
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_AssociatePRs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	t0 := "2023-08-29T19:20:49+01:00"
+	t0p, terr := time.Parse(time.RFC3339, t0)
+	assert.NoError(t, terr)
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"query($ids:[ID!]!){nodes(ids: $ids){... on Ref{id,associatedPullRequests(first: 5, orderBy: {field: CREATED_AT, direction: DESC}){nodes{id,number,state,url,author{login},isCrossRepository,createdAt}}}}}","variables":{"ids":["a","b"]}}`)
+		writeBody(t, w, fmt.Sprintf(`{"data":{"nodes":[{"id":"a","associatedPullRequests":{"nodes":[{"id":"p","number":7,"state":"CLOSED","url":"u","author":{"login":"dependabot"},"createdAt":"%v"}]}},{"id":"b","associatedPullRequests":{"nodes":[]}}]}}`, t0))
+	})
+	t.Run("associate-prs-valid", func(ti *testing.T) {
+		refs := []*api.GitHubRef{{Id: "a"}, {Id: "b"}}
+		assert.NoError(ti, ghApi.AssociatePRs(context.Background(), refs...))
+		assert.Equal(ti, []*api.GitHubRefPR{{Id: "p", Number: 7, State: "CLOSED", Url: "u", Author: "dependabot", CreatedAt: t0p}}, refs[0].PullRequests)
+		assert.Empty(ti, refs[1].PullRequests)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_ViewerComments(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"query($ids:[ID!]!){nodes(ids: $ids){... on PullRequest{id,comments(last: 100){nodes{body,viewerDidAuthor}}}}}","variables":{"ids":["p","q"]}}`)
+		writeBody(t, w, `{"data":{"nodes":[{"id":"p","comments":{"nodes":[{"body":"mine","viewerDidAuthor":true},{"body":"theirs","viewerDidAuthor":false}]}},{"id":"q","comments":{"nodes":[]}}]}}`)
+	})
+	t.Run("viewer-comments-valid", func(ti *testing.T) {
+		comments, err := ghApi.ViewerComments(context.Background(), "p", "q")
+		assert.NoError(ti, err)
+		assert.Equal(ti, map[string][]string{"p": {"mine"}}, comments)
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_GetPRs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"query($ids:[ID!]!){nodes(ids: $ids){... on PullRequest{id,number,url,commits(last: 1){nodes{commit{committedDate}}},baseRefName,headRefName,headRefOid,state}}}","variables":{"ids":["a"]}}`)
+		writeBody(t, w, `{"data":{"nodes":[{"id":"a","number":7,"url":"u","commits":{"nodes":[]},"baseRefName":"main","headRefName":"feature","headRefOid":"abc","state":"CLOSED"}]}}`)
+	})
+	t.Run("get-prs-valid-w/o-commits", func(ti *testing.T) {
+		prs, err := ghApi.GetPRs(context.Background(), "a")
+		assert.NoError(ti, err)
+		assert.Len(ti, prs, 1)
+		assert.Equal(ti, &api.GitHubPR{Id: "a", Number: 7, Url: "u", Source: "feature", Target: "main", HeadSha: "abc", State: "CLOSED"}, prs["a"])
+	})
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_ListRepositories(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	login := "x"
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		body := readBody(t, r)
+		if strings.Contains(body, `"after":null`) {
+			assert.Equal(t,
+				body,
+				fmt.Sprintf(`{"query":"query($after:String$first:Int!$login:String!){repositoryOwner(login: $login){repositories(first: $first, after: $after, ownerAffiliations: [OWNER]){nodes{id,name,owner{login},isArchived,isFork,visibility,repositoryTopics(first: 20){nodes{topic{name}}}},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"login":"%v"}}`, login))
+			writeBody(t, w, `{"data":{"repositoryOwner":{"repositories":{"nodes":[{"id":"1","name":"a","owner":{"login":"x"},"isArchived":true,"visibility":"PRIVATE","repositoryTopics":{"nodes":[{"topic":{"name":"go"}}]}}],"pageInfo":{"endCursor":"c1","hasNextPage":true}}}}}`)
+			return
+		}
+		assert.Contains(t, body, `"after":"c1"`)
+		writeBody(t, w, `{"data":{"repositoryOwner":{"repositories":{"nodes":[{"id":"2","name":"b","owner":{"login":"x"},"isFork":true,"visibility":"PUBLIC"}],"pageInfo":{"hasNextPage":false}}}}}`)
+	})
+	{
+		t.Run("list-repositories-paginated", func(ti *testing.T) {
+			repos, err := ghApi.ListRepositories(context.Background(), login)
+			assert.NoError(ti, err)
+			assert.Equal(ti, []*api.GitHubRepository{
+				{Id: "1", Owner: "x", Name: "a", Archived: true, Visibility: "PRIVATE", Topics: []string{"go"}},
+				{Id: "2", Owner: "x", Name: "b", Fork: true, Visibility: "PUBLIC"},
+			}, repos)
+		})
+		t.Run("list-repositories-invalid-login", func(ti *testing.T) {
+			repos, err := ghApi.ListRepositories(context.Background(), "")
+			assert.Error(ti, err)
+			assert.Nil(ti, repos)
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_ListReleaseTags(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	owner, repo := "x", "y"
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			fmt.Sprintf(`{"query":"query($after:String$first:Int!$name:String!$owner:String!){repository(owner: $owner, name: $name){releases(first: $first, after: $after){nodes{tagName},pageInfo{endCursor,hasNextPage}}}}","variables":{"after":null,"first":100,"name":"%v","owner":"%v"}}`, repo, owner))
+		writeBody(t, w, `{"data":{"repository":{"releases":{"nodes":[{"tagName":"v1.0.0"},{"tagName":"v1.1.0"}],"pageInfo":{"hasNextPage":false}}}}}`)
+	})
+	{
+		t.Run("list-release-tags", func(ti *testing.T) {
+			tags, err := ghApi.ListReleaseTags(context.Background(), owner, repo)
+			assert.NoError(ti, err)
+			assert.Equal(ti, []string{"v1.0.0", "v1.1.0"}, tags)
+		})
+		t.Run("list-release-tags-invalid-repo", func(ti *testing.T) {
+			tags, err := ghApi.ListReleaseTags(context.Background(), owner, "")
+			assert.Error(ti, err)
+			assert.Nil(ti, tags)
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestParseSemver(t *testing.T) {
+	for name, expected := range map[string]*api.Semver{
+		"v1.2.3":           {Major: 1, Minor: 2, Patch: 3},
+		"1.2.3-rc.1":       {Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"},
+		"v2.0.0-beta+b123": {Major: 2, Prerelease: "beta"},
+		"v3.1":             {Major: 3, Minor: 1},
+	} {
+		v, err := api.ParseSemver(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, v, name)
+	}
+	for _, name := range []string{"latest", "v1.2.3.4", "v01.2.3", "v1.2.3-", "release-1"} {
+		_, err := api.ParseSemver(name)
+		assert.Error(t, err, name)
+	}
+
+	// ordered by ascending precedence
+	ordered := []string{"v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0-rc.1", "v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := api.ParseSemver(ordered[i-1])
+		b, _ := api.ParseSemver(ordered[i])
+		assert.Equal(t, -1, a.Compare(b), ordered[i-1]+" < "+ordered[i])
+		assert.Equal(t, 1, b.Compare(a), ordered[i]+" > "+ordered[i-1])
+		assert.Equal(t, 0, a.Compare(a))
+	}
+}
+
 func TestGitHub_DeleteRefs(t *testing.T) {
 	envKey := "GITHUB_TOKEN"
 	old := os.Getenv(envKey)
@@ -271,6 +736,267 @@ func TestGitHub_ClosePRs(t *testing.T) {
 	assert.NoError(t, os.Setenv(envKey, old))
 }
 
+func TestGitHub_CreateRef(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"mutation($input:CreateRefInput!){createRef(input: $input){ref{id,repository{nameWithOwner}}}}","variables":{"input":{"repositoryId":"r","name":"refs/heads/x","oid":"abc"}}}`)
+		writeBody(t, w, `{"data":{}}`)
+	})
+	{
+		t.Run("create-ref-valid", func(ti *testing.T) {
+			assert.NoError(ti,
+				ghApi.CreateRef(context.Background(), "r", "refs/heads/x", "abc"))
+		})
+		t.Run("create-ref-invalid-empty", func(ti *testing.T) {
+			assert.Error(ti,
+				ghApi.CreateRef(context.Background(), "r", "refs/heads/x", ""))
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_ReopenPRs(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	identifier := "x"
+
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			fmt.Sprintf(`{"query":"mutation($input:ID!){reopenPullRequest(input: {pullRequestId: $input}){typename :__typename}}","variables":{"input":"%v"}}`, identifier))
+		writeBody(t, w, `{"data":{}}`)
+	})
+	{
+		t.Run("reopen-prs-valid", func(ti *testing.T) {
+			assert.NoError(ti,
+				ghApi.ReopenPRs(context.Background(), identifier))
+		})
+		t.Run("reopen-prs-invalid-empty", func(ti *testing.T) {
+			assert.Error(ti,
+				ghApi.ReopenPRs(context.Background()))
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_AddComment(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t,
+			readBody(t, r),
+			`{"query":"mutation($input:AddCommentInput!){addComment(input: $input){typename :__typename}}","variables":{"input":{"subjectId":"x","body":"stale"}}}`)
+		writeBody(t, w, `{"data":{}}`)
+	})
+	{
+		t.Run("add-comment-valid", func(ti *testing.T) {
+			assert.NoError(ti,
+				ghApi.AddComment(context.Background(), "x", "stale"))
+		})
+		t.Run("add-comment-invalid-empty", func(ti *testing.T) {
+			assert.Error(ti,
+				ghApi.AddComment(context.Background(), "x", " "))
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestGitHub_Labels(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	setup(t)
+	mux.HandleFunc("/repos/x/y/issues/7/labels", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, `["stale"]`, readBody(t, r))
+		writeBody(t, w, `[{"name":"stale"}]`)
+	})
+	mux.HandleFunc("/repos/x/y/issues/7/labels/stale", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(http.StatusOK)
+	})
+	{
+		t.Run("add-labels-valid", func(ti *testing.T) {
+			assert.NoError(ti,
+				ghApi.AddLabels(context.Background(), "x", "y", 7, "stale"))
+		})
+		t.Run("add-labels-invalid-empty", func(ti *testing.T) {
+			assert.Error(ti,
+				ghApi.AddLabels(context.Background(), "x", "y", 7))
+		})
+		t.Run("remove-label-valid", func(ti *testing.T) {
+			assert.NoError(ti,
+				ghApi.RemoveLabel(context.Background(), "x", "y", 7, "stale"))
+		})
+		t.Run("remove-label-missing", func(ti *testing.T) {
+			assert.Error(ti,
+				ghApi.RemoveLabel(context.Background(), "x", "y", 8, "stale"))
+		})
+	}
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
+func TestManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m := api.NewManifest(path)
+	assert.NoError(t, m.RecordRefs("x/y", api.BranchRefType, &api.GitHubRef{Id: "1", Name: "feature", Sha: "abc"}))
+	assert.NoError(t, m.RecordRefs("x/y", api.TagRefType, &api.GitHubRef{Id: "2", Name: "v1.0.0", Sha: "def"}))
+	assert.NoError(t, m.RecordPRs("x/y", &api.GitHubPR{Id: "3", Source: "feature", Number: 7}))
+
+	read, err := api.ReadManifest(path)
+	assert.NoError(t, err)
+	assert.Len(t, read.Entries, 3)
+	for i, expected := range []*api.ManifestEntry{
+		{Repository: "x/y", Type: api.BranchManifestEntry, Id: "1", Name: "feature", Sha: "abc"},
+		{Repository: "x/y", Type: api.TagManifestEntry, Id: "2", Name: "v1.0.0", Sha: "def"},
+		{Repository: "x/y", Type: api.PRManifestEntry, Id: "3", Name: "feature", Number: 7},
+	} {
+		assert.False(t, read.Entries[i].Timestamp.IsZero())
+		expected.Timestamp = read.Entries[i].Timestamp
+		assert.Equal(t, expected, read.Entries[i])
+	}
+
+	_, err = api.ReadManifest(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o600))
+
+	l := api.NewAuditLog(path)
+	assert.NoError(t, l.Audit(&api.AuditEntry{Id: "1", Action: api.DeleteRefAuditAction, Result: api.SuccessAuditResult}))
+	assert.NoError(t, l.Audit(&api.AuditEntry{Id: "2", Action: api.ClosePRAuditAction, Result: api.FailureAuditResult, Error: "boom"}))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{}
+{"timestamp":"0001-01-01T00:00:00Z","id":"1","action":"delete_ref","result":"success"}
+{"timestamp":"0001-01-01T00:00:00Z","id":"2","action":"close_pr","result":"failure","error":"boom"}
+`, string(content))
+
+	assert.Error(t, api.NewAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl")).Audit(&api.AuditEntry{}))
+}
+
+type auditRecorder struct {
+	entries []*api.AuditEntry
+}
+
+func (a *auditRecorder) Audit(entries ...*api.AuditEntry) error {
+	a.entries = append(a.entries, entries...)
+	return nil
+}
+
+func TestGitHub_Audit(t *testing.T) {
+	envKey := "GITHUB_TOKEN"
+	old := os.Getenv(envKey)
+	assert.NoError(t, os.Setenv(envKey, "XXX"))
+
+	recorder := new(auditRecorder)
+	mux = http.NewServeMux()
+	inst, err := api.NewSession(
+		api.WithAuditor(recorder),
+		api.WithHttpClient(&http.Client{Transport: &httpTestServer{handler: mux}}))
+	assert.NoError(t, err)
+
+	var queries []string
+	var mu sync.Mutex
+	handler(func(w http.ResponseWriter, r *http.Request) {
+		body := readBody(t, r)
+		mu.Lock()
+		queries = append(queries, body)
+		mu.Unlock()
+		switch {
+		case strings.Contains(body, "viewer"):
+			assert.Equal(t, `{"query":"{viewer{login}}"}`, body)
+			writeBody(t, w, `{"data":{"viewer":{"login":"octocat"}}}`)
+		case strings.Contains(body, "nodes"):
+			assert.Contains(t, body, `{"query":"query($ids:[ID!]!){nodes(ids: $ids){typename :__typename,... on Ref{id,name,prefix,target{oid},repository{nameWithOwner}},... on PullRequest{id,number,headRefName,headRefOid,repository{nameWithOwner}}}}"`)
+			writeBody(t, w, `{"data":{"nodes":[
+				{"typename":"Ref","id":"a","name":"feature","prefix":"refs/heads/","target":{"oid":"abc"},"repository":{"nameWithOwner":"x/y"}},
+				{"typename":"Ref","id":"b","name":"v1.0.0","prefix":"refs/tags/","target":{"oid":"def"},"repository":{"nameWithOwner":"x/y"}},
+				null]}}`)
+		case strings.Contains(body, `"input":"b"`):
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.Contains(body, "createRef"):
+			writeBody(t, w, `{"data":{"createRef":{"ref":{"id":"d","repository":{"nameWithOwner":"x/y"}}}}}`)
+		default:
+			writeBody(t, w, `{"data":{}}`)
+		}
+	})
+
+	err = inst.DeleteRefs(context.Background(), "a", "b", "c")
+	assert.ErrorContains(t, err, "unable to delete ref: b")
+	assert.Len(t, recorder.entries, 3)
+	for i, expected := range []*api.AuditEntry{
+		{Repository: "x/y", Type: api.BranchManifestEntry, Id: "a", Name: "feature", Sha: "abc", Result: api.SuccessAuditResult},
+		{Repository: "x/y", Type: api.TagManifestEntry, Id: "b", Name: "v1.0.0", Sha: "def", Result: api.FailureAuditResult},
+		{Id: "c", Result: api.SuccessAuditResult},
+	} {
+		entry := recorder.entries[i]
+		assert.False(t, entry.Timestamp.IsZero())
+		assert.Equal(t, "octocat", entry.Viewer)
+		assert.Equal(t, api.DeleteRefAuditAction, entry.Action)
+		assert.Equal(t, expected.Result, entry.Result)
+		assert.Equal(t, expected.Id, entry.Id)
+		assert.Equal(t, expected.Repository, entry.Repository)
+		assert.Equal(t, expected.Type, entry.Type)
+		assert.Equal(t, expected.Name, entry.Name)
+		assert.Equal(t, expected.Sha, entry.Sha)
+	}
+	assert.Contains(t, recorder.entries[1].Error, "500")
+
+	// the viewer is resolved once per session
+	assert.NoError(t, inst.ClosePRs(context.Background(), "a"))
+	var viewerQueries int
+	for _, query := range queries {
+		if strings.Contains(query, "viewer") {
+			viewerQueries++
+		}
+	}
+	assert.Equal(t, 1, viewerQueries)
+	assert.Len(t, recorder.entries, 4)
+	assert.Equal(t, api.ClosePRAuditAction, recorder.entries[3].Action)
+
+	// created refs are described by the mutation itself
+	assert.NoError(t, inst.CreateRef(context.Background(), "r", "refs/tags/v1.0.0", "def"))
+	assert.Len(t, recorder.entries, 5)
+	assert.Equal(t, &api.AuditEntry{Timestamp: recorder.entries[4].Timestamp, Viewer: "octocat", User: recorder.entries[4].User,
+		Host: recorder.entries[4].Host, Repository: "x/y", Type: api.TagManifestEntry, Id: "d", Name: "v1.0.0", Sha: "def",
+		Action: api.CreateRefAuditAction, Result: api.SuccessAuditResult}, recorder.entries[4])
+	var nodesQueries int
+	for _, query := range queries {
+		if strings.Contains(query, "nodes") {
+			nodesQueries++
+		}
+	}
+	assert.Equal(t, 2, nodesQueries)
+
+	// a failure to audit is not reported as a failed mutation
+	inst, err = api.NewSession(
+		api.WithAuditor(api.NewAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl"))),
+		api.WithHttpClient(&http.Client{Transport: &httpTestServer{handler: mux}}))
+	assert.NoError(t, err)
+	assert.NoError(t, inst.DeleteRefs(context.Background(), "a"))
+	assert.NoError(t, os.Setenv(envKey, old))
+}
+
 /********************************/
 
 var (
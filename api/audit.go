@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AuditAction = string
+
+const (
+	DeleteRefAuditAction   AuditAction = "delete_ref"
+	CreateRefAuditAction               = "create_ref"
+	ClosePRAuditAction                 = "close_pr"
+	ReopenPRAuditAction                = "reopen_pr"
+	CommentAuditAction                 = "add_comment"
+	AddLabelsAuditAction               = "add_labels"
+	RemoveLabelAuditAction             = "remove_label"
+)
+
+type AuditResult = string
+
+const (
+	SuccessAuditResult AuditResult = "success"
+	FailureAuditResult             = "failure"
+)
+
+// AuditEntry records a single mutation: who carried it out, on what and with which result. The user & host identify
+// who ran the tool while the viewer is the login of the token (or GitHub App) the mutation was authenticated with.
+type AuditEntry struct {
+	Timestamp  time.Time         `json:"timestamp"`
+	User       string            `json:"user,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Viewer     string            `json:"viewer,omitempty"`
+	Repository string            `json:"repository,omitempty"`
+	Type       ManifestEntryType `json:"type,omitempty"`
+	Id         string            `json:"id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Sha        string            `json:"sha,omitempty"`
+	Number     int               `json:"number,omitempty"`
+	Labels     []string          `json:"labels,omitempty"`
+	Action     AuditAction       `json:"action"`
+	Result     AuditResult       `json:"result"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Auditor records the mutations carried out by the session.
+type Auditor interface {
+	Audit(entries ...*AuditEntry) error
+}
+
+// Auditors records the mutations through every auditor, joining their errors.
+type Auditors []Auditor
+
+func (a Auditors) Audit(entries ...*AuditEntry) error {
+	var err error
+	for _, auditor := range a {
+		err = errors.Join(err, auditor.Audit(entries...))
+	}
+	return err
+}
+
+// AuditLog appends the entries to a JSON lines file. The file is created if needed and never truncated.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+func (l *AuditLog) Audit(entries ...*AuditEntry) error {
+	content, err := auditLines(entries...)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log: %v. error: %v", l.path, err)
+	}
+	defer f.Close()
+	if _, err = f.Write(content); err != nil {
+		return fmt.Errorf("unable to write audit log: %v. error: %v", l.path, err)
+	}
+	return nil
+}
+
+func auditLines(entries ...*AuditEntry) ([]byte, error) {
+	var out []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(append(out, line...), '\n')
+	}
+	return out, nil
+}
+
+// WithAuditor records every mutation carried out by the session through the auditor.
+func WithAuditor(auditor Auditor) Option {
+	return func(session *GitHub) {
+		session.auditor = auditor
+	}
+}
+
+// auditIdentity resolves (once) who runs the tool and the login of the token used by the session. The viewer is left
+// empty when it cannot be resolved, e.g. for GitHub App installation tokens lacking the permission.
+func (gh *GitHub) auditIdentity(ctx context.Context) {
+	gh.identityOnce.Do(func() {
+		if u, err := user.Current(); err == nil {
+			gh.identity.User = u.Username
+		}
+		gh.identity.Host, _ = os.Hostname()
+
+		var query struct {
+			Viewer struct {
+				Login string
+			}
+		}
+		if err := gh.clientV4.Query(ctx, &query, nil); err == nil {
+			gh.identity.Viewer = query.Viewer.Login
+		}
+	})
+}
+
+// audit records the entries of the action along with their results, i.e. the error of the mutation of the entry with
+// the same index (if any). A failure to record them is logged rather than returned, so that it is never mistaken for a
+// failed mutation.
+func (gh *GitHub) audit(ctx context.Context, action AuditAction, entries []*AuditEntry, results []error) {
+	if gh.auditor == nil || len(entries) == 0 {
+		return
+	}
+	gh.auditIdentity(ctx)
+
+	now := time.Now()
+	for i, entry := range entries {
+		entry.Timestamp = now
+		entry.User, entry.Host, entry.Viewer = gh.identity.User, gh.identity.Host, gh.identity.Viewer
+		entry.Action = action
+		entry.Result = SuccessAuditResult
+		if i < len(results) && results[i] != nil {
+			entry.Result = FailureAuditResult
+			entry.Error = results[i].Error()
+		}
+	}
+	if err := gh.auditor.Audit(entries...); err != nil {
+		log.Printf("unable to audit: %v. error: %v", action, err)
+	}
+}
+
+// describe fetches the details of the refs & PRs with the provided node ids before they are mutated. It
+// returns one entry per id, in order. The entries only carry their id when the details cannot be fetched.
+func (gh *GitHub) describe(ctx context.Context, ids ...string) []*AuditEntry {
+	if gh.auditor == nil {
+		return nil
+	}
+
+	var query struct {
+		Nodes []struct {
+			Typename string `graphql:"typename :__typename"`
+			Ref      struct {
+				Id         string
+				Name       string
+				Prefix     string
+				Target     struct{ Oid string }
+				Repository struct{ NameWithOwner string }
+			} `graphql:"... on Ref"`
+			PullRequest struct {
+				Id          string
+				Number      int
+				HeadRefName string
+				HeadRefOid  string
+				Repository  struct{ NameWithOwner string }
+			} `graphql:"... on PullRequest"`
+		} `graphql:"nodes(ids: $ids)"`
+	}
+
+	described := make(map[string]*AuditEntry, len(ids))
+	for _, b := range batches(ids, _nodesBatchSize) {
+		if err := gh.clientV4.Query(ctx, &query, map[string]interface{}{"ids": b}); err != nil {
+			break
+		}
+		for _, n := range query.Nodes {
+			switch n.Typename {
+			case "Ref":
+				entryType := BranchManifestEntry
+				if strings.HasPrefix(n.Ref.Prefix, "refs/tags/") {
+					entryType = TagManifestEntry
+				}
+				described[n.Ref.Id] = &AuditEntry{Repository: n.Ref.Repository.NameWithOwner, Type: entryType,
+					Id: n.Ref.Id, Name: n.Ref.Name, Sha: n.Ref.Target.Oid}
+			case "PullRequest":
+				described[n.PullRequest.Id] = &AuditEntry{Repository: n.PullRequest.Repository.NameWithOwner,
+					Type: PRManifestEntry, Id: n.PullRequest.Id, Name: n.PullRequest.HeadRefName,
+					Sha: n.PullRequest.HeadRefOid, Number: n.PullRequest.Number}
+			}
+		}
+	}
+
+	out := make([]*AuditEntry, len(ids))
+	for i, id := range ids {
+		out[i] = described[id]
+		if out[i] == nil {
+			out[i] = &AuditEntry{Id: id}
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+//go:build !windows && !plan9
+
+package api
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// SyslogAuditor sends every entry as a JSON message to the local syslog daemon (auth facility).
+type SyslogAuditor struct {
+	writer *syslog.Writer
+}
+
+func NewSyslogAuditor(tag string) (*SyslogAuditor, error) {
+	writer, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to syslog. error: %v", err)
+	}
+	return &SyslogAuditor{writer: writer}, nil
+}
+
+func (s *SyslogAuditor) Audit(entries ...*AuditEntry) error {
+	content, err := auditLines(entries...)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if err = s.writer.Notice(line); err != nil {
+			return fmt.Errorf("unable to write to syslog. error: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package api
+
+import "fmt"
+
+// SyslogAuditor is not supported on this platform.
+type SyslogAuditor struct{}
+
+func NewSyslogAuditor(_ string) (*SyslogAuditor, error) {
+	return nil, fmt.Errorf("syslog is not supported on this platform")
+}
+
+func (s *SyslogAuditor) Audit(_ ...*AuditEntry) error {
+	return fmt.Errorf("syslog is not supported on this platform")
+}
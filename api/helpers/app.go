@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// _appJwtLifetime is kept below the 10 minutes maximum accepted by GitHub.
+	_appJwtLifetime = 9 * time.Minute
+	// _appJwtClockSkew backdates the JWT issue time to tolerate clock drift.
+	_appJwtClockSkew = time.Minute
+	// _appTokenEarlyExpiry refreshes installation tokens before they expire.
+	_appTokenEarlyExpiry = 5 * time.Minute
+)
+
+type appTokenSource struct {
+	ctx            context.Context
+	client         *github.Client
+	appId          int64
+	installationId int64
+	key            *rsa.PrivateKey
+}
+
+// NewAppTokenSource returns an oauth2.TokenSource that exchanges a GitHub App JWT for an installation token.
+// Tokens are cached and automatically refreshed shortly before they expire.
+func NewAppTokenSource(ctx context.Context, enterpriseEndpoint string, appId, installationId int64, privateKey []byte) (oauth2.TokenSource, error) {
+	if appId == 0 {
+		return nil, fmt.Errorf("a GitHub App id must be specified")
+	}
+	if installationId == 0 {
+		return nil, fmt.Errorf("a GitHub App installation id must be specified")
+	}
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	inst := &appTokenSource{
+		ctx:            ctx,
+		appId:          appId,
+		installationId: installationId,
+		key:            key,
+	}
+	httpClient := &http.Client{Transport: &appJwtTransport{source: inst}}
+	if len(enterpriseEndpoint) != 0 {
+		inst.client, err = github.NewEnterpriseClient(enterpriseEndpoint, enterpriseEndpoint, httpClient)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		inst.client = github.NewClient(httpClient)
+	}
+	return oauth2.ReuseTokenSourceWithExpiry(nil, inst, _appTokenEarlyExpiry), nil
+}
+
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+	req, err := a.client.NewRequest(http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", a.installationId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := new(github.InstallationToken)
+	if _, err = a.client.Do(a.ctx, req, token); err != nil {
+		return nil, fmt.Errorf("unable to create GitHub App installation token. error: %v", err)
+	}
+	if len(token.GetToken()) == 0 {
+		return nil, fmt.Errorf("the GitHub App installation token response did not contain a token")
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt(),
+	}, nil
+}
+
+func (a *appTokenSource) jwt() (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-_appJwtClockSkew).Unix(),
+		"exp": now.Add(_appJwtLifetime).Unix(),
+		"iss": strconv.FormatInt(a.appId, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign the GitHub App JWT. error: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+type appJwtTransport struct {
+	source *appTokenSource
+}
+
+func (t *appJwtTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := t.source.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req := r.Clone(r.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func parsePrivateKey(privateKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, fmt.Errorf("the GitHub App private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the GitHub App private key. error: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the GitHub App private key must be an RSA key")
+	}
+	return key, nil
+}
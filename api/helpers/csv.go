@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// CsvSerializer renders views as CSV records, one per ref or PR, with a header line.
+type CsvSerializer struct {
+	Columns []string
+}
+
+func (c *CsvSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if s, ok := a.(string); ok {
+		return []byte(s), nil
+	}
+
+	header, rows, err := records(a, c.Columns)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err = w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatRecord(value)
+		}
+		if err = w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), w.Error()
+}
@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+)
+
+type findingLevel = string
+
+const (
+	noteLevel    findingLevel = "note"
+	warningLevel              = "warning"
+	errorLevel                = "error"
+)
+
+// finding is a stale ref or PR reported to CI systems.
+type finding struct {
+	rule       string
+	kind       string
+	repository string
+	name       string
+	uri        string
+	date       *time.Time
+	level      findingLevel
+}
+
+func (f *finding) message() string {
+	if f.date == nil {
+		return fmt.Sprintf("The %v [%v] of [%v] is stale", f.kind, f.name, f.repository)
+	}
+	return fmt.Sprintf("The %v [%v] of [%v] is stale: last activity %v (%v)", f.kind, f.name, f.repository,
+		Age(*f.date), f.date.Format(time.RFC3339))
+}
+
+// findings turns every entry of the view into a finding. The kind (branch, tag or pr) applies to the entries without a
+// 'type' field and the level is derived from the age of the entries relative to the threshold: 'note' until twice the
+// threshold, 'warning' until four times the threshold and 'error' afterwards. It also returns the repositories of the
+// view in order, including the ones without findings.
+func findings(a any, kind string, threshold time.Duration) ([]*finding, []string) {
+	var out []*finding
+	var repositories []string
+	seen := make(map[string]bool)
+	for _, s := range flatten(a) {
+		if s.columns == nil {
+			continue
+		}
+		var repository string
+		if len(s.keys) != 0 {
+			repository = s.keys[len(s.keys)-1]
+		}
+		for _, row := range s.rows {
+			f := newFinding(s.columns, row, repository, kind, threshold)
+			if !seen[f.repository] {
+				seen[f.repository] = true
+				repositories = append(repositories, f.repository)
+			}
+			out = append(out, f)
+		}
+		if len(s.rows) == 0 && len(repository) != 0 && !seen[repository] {
+			seen[repository] = true
+			repositories = append(repositories, repository)
+		}
+	}
+	return out, repositories
+}
+
+func newFinding(columns []string, row []any, repository, kind string, threshold time.Duration) *finding {
+	fields := make(map[string]any)
+	for i, name := range columns {
+		fields[name] = row[i]
+	}
+	if value, ok := fields["repository"].(string); ok && len(value) != 0 {
+		repository = value
+	}
+	if value, ok := fields["type"].(string); ok && len(value) != 0 {
+		kind = value
+	}
+	if len(kind) == 0 {
+		kind = "ref"
+		if _, ok := fields["number"]; ok {
+			kind = "pr"
+		}
+	}
+
+	f := &finding{rule: "stale-" + kind, kind: kind, repository: repository, level: warningLevel}
+	f.name = formatRecord(fields["name"])
+	if number, ok := fields["number"]; ok && number != nil && number != 0 {
+		f.name = fmt.Sprintf("#%v", number)
+		if source := formatRecord(fields["source"]); len(source) != 0 {
+			f.name += " " + source
+		}
+	}
+	switch url := formatRecord(fields["url"]); {
+	case len(url) != 0:
+		f.uri = url
+	case kind == "tag":
+		f.uri = "refs/tags/" + f.name
+	default:
+		f.uri = "refs/heads/" + f.name
+	}
+
+	if date, ok := rowDate(row); ok {
+		f.date = &date
+		if threshold > 0 {
+			switch age := time.Since(date); {
+			case age < 2*threshold:
+				f.level = noteLevel
+			case age < 4*threshold:
+				f.level = warningLevel
+			default:
+				f.level = errorLevel
+			}
+		}
+	}
+	return f
+}
@@ -0,0 +1,205 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// section is a table of rows flattened out of a view. The path holds the map keys & field names leading to the rows
+// while the keys only hold the map keys, e.g. the repository of the stale refs. The meta holds the scalar fields of the
+// struct the rows belong to (if any).
+type section struct {
+	path    []string
+	keys    []string
+	meta    [][2]any
+	columns []string
+	rows    [][]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// flatten walks the provided view, turning every list of structs into a section. Maps are walked in key order.
+// Cells hold scalar values only: strings, numbers, booleans, times or nil.
+func flatten(a any) []*section {
+	var out []*section
+	walk(reflect.ValueOf(a), nil, nil, &out)
+	return out
+}
+
+func walk(v reflect.Value, path, keys []string, out *[]*section) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && isComposite(v.Type().Elem()):
+		mapKeys := v.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return fmt.Sprint(mapKeys[i].Interface()) < fmt.Sprint(mapKeys[j].Interface())
+		})
+		for _, key := range mapKeys {
+			name := fmt.Sprint(key.Interface())
+			walk(v.MapIndex(key), append(append([]string{}, path...), name), append(append([]string{}, keys...), name), out)
+		}
+	case v.Kind() == reflect.Map:
+		s := &section{path: path, keys: keys, columns: []string{"key", "value"}}
+		for _, key := range v.MapKeys() {
+			s.rows = append(s.rows, []any{fmt.Sprint(key.Interface()), scalar(v.MapIndex(key))})
+		}
+		sort.Slice(s.rows, func(i, j int) bool {
+			return s.rows[i][0].(string) < s.rows[j][0].(string)
+		})
+		*out = append(*out, s)
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		*out = append(*out, table(v, path, keys))
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		s := &section{path: path, keys: keys}
+		var nested []reflect.Value
+		var names []string
+		for _, field := range fields(v.Type()) {
+			value := indirect(v.Field(field))
+			if value.IsValid() && isComposite(value.Type()) && !isScalarList(value) {
+				nested = append(nested, value)
+				names = append(names, fieldName(v.Type().Field(field)))
+				continue
+			}
+			s.meta = append(s.meta, [2]any{fieldName(v.Type().Field(field)), scalar(value)})
+		}
+		*out = append(*out, s)
+		for i, value := range nested {
+			walk(value, append(append([]string{}, path...), names[i]), keys, out)
+		}
+	default:
+		*out = append(*out, &section{path: path, keys: keys, columns: []string{"value"}, rows: [][]any{{scalar(v)}}})
+	}
+}
+
+// table turns a list into a section: one column per (JSON) field of struct elements or a single value column.
+func table(v reflect.Value, path, keys []string) *section {
+	s := &section{path: path, keys: keys}
+	elemType := v.Type().Elem()
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct || elemType == timeType {
+		s.columns = []string{"value"}
+		for i := 0; i < v.Len(); i++ {
+			s.rows = append(s.rows, []any{scalar(v.Index(i))})
+		}
+		return s
+	}
+
+	structFields := fields(elemType)
+	for _, field := range structFields {
+		s.columns = append(s.columns, fieldName(elemType.Field(field)))
+	}
+	for i := 0; i < v.Len(); i++ {
+		elem := indirect(v.Index(i))
+		row := make([]any, len(structFields))
+		if elem.IsValid() {
+			for j, field := range structFields {
+				row[j] = scalar(elem.Field(field))
+			}
+		}
+		s.rows = append(s.rows, row)
+	}
+	return s
+}
+
+// scalar reduces the value to a cell: lists are joined, maps are reduced to their keys and nested structs to their
+// first non-empty field other than their id.
+func scalar(v reflect.Value) any {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			if value := scalar(v.Index(i)); value != nil {
+				values = append(values, fmt.Sprint(value))
+			}
+		}
+		return strings.Join(values, ", ")
+	case reflect.Map:
+		var keys []string
+		for _, key := range v.MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, ", ")
+	case reflect.Struct:
+		for _, field := range fields(v.Type()) {
+			if strings.EqualFold(fieldName(v.Type().Field(field)), "id") {
+				continue
+			}
+			if value := scalar(v.Field(field)); value != nil && !reflect.ValueOf(value).IsZero() {
+				return value
+			}
+		}
+		return nil
+	case reflect.Interface:
+		return scalar(v.Elem())
+	}
+	return v.Interface()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isComposite(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return true
+	case reflect.Struct:
+		return t != timeType
+	}
+	return false
+}
+
+// isScalarList reports whether the value is a list of scalars, e.g. labels, that fits in a single cell.
+func isScalarList(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && !isComposite(v.Type().Elem())
+}
+
+// fields returns the indexes of the exported fields of the struct type that are serialised to JSON.
+func fields(t reflect.Type) []int {
+	var out []int
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() && t.Field(i).Tag.Get("json") != "-" {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); len(name) != 0 {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
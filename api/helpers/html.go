@@ -0,0 +1,218 @@
+package helpers
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+// histogramBuckets are the upper bounds of the age histogram buckets. Older entries fall into the last bucket.
+var histogramBuckets = []struct {
+	label string
+	upTo  time.Duration
+}{
+	{"< 1 month", 30 * 24 * time.Hour},
+	{"1-3 months", 90 * 24 * time.Hour},
+	{"3-6 months", 180 * 24 * time.Hour},
+	{"6-12 months", 365 * 24 * time.Hour},
+	{"> 1 year", 0},
+}
+
+// HtmlSerializer renders views as a self-contained HTML report: a summary and an age histogram per repository along
+// with a sortable table of its entries. Entries flagged as removed are reported as such while the remaining ones are
+// reported as to be removed, unless flagged as protected.
+type HtmlSerializer struct {
+	// Title of the report, e.g. the command that produced the view.
+	Title string
+	// Columns restricts the columns of the tables (in order). All non-empty columns but the ids are rendered by default.
+	Columns []string
+}
+
+type htmlReport struct {
+	Title     string
+	Generated string
+	Total     int
+	Removed   int
+	Removable int
+	Histogram []*htmlBucket
+	Sections  []*htmlSection
+	Text      string
+}
+
+type htmlSection struct {
+	Title     string
+	Meta      [][2]string
+	Columns   []string
+	Rows      [][]*htmlCell
+	Removed   int
+	Removable int
+	Histogram []*htmlBucket
+}
+
+type htmlCell struct {
+	Text  string
+	Sort  string
+	Title string
+}
+
+type htmlBucket struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+func (h *HtmlSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	tpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &htmlReport{
+		Title:     h.Title,
+		Generated: time.Now().Format(time.RFC1123),
+		Histogram: newHistogram(),
+	}
+	if s, ok := a.(string); ok {
+		report.Text = s
+	} else {
+		sections := flatten(a)
+		if err = checkColumns(sections, h.Columns); err != nil {
+			return nil, err
+		}
+		for _, s := range sections {
+			report.Sections = append(report.Sections, h.section(s, report))
+		}
+	}
+	for _, histogram := range append([][]*htmlBucket{report.Histogram}, sectionHistograms(report.Sections)...) {
+		scale(histogram)
+	}
+
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *HtmlSerializer) section(s *section, report *htmlReport) *htmlSection {
+	out := &htmlSection{Title: strings.Join(s.path, " / "), Histogram: newHistogram()}
+	for _, kv := range s.meta {
+		if value := formatRecord(kv[1]); len(value) != 0 {
+			out.Meta = append(out.Meta, [2]string{fmt.Sprint(kv[0]), value})
+		}
+	}
+	if s.columns == nil {
+		return out
+	}
+
+	indexes := selectColumns(s, h.Columns)
+	for _, index := range indexes {
+		out.Columns = append(out.Columns, s.columns[index])
+	}
+	protected, removed := -1, -1
+	for i, name := range s.columns {
+		switch name {
+		case "protected":
+			protected = i
+		case "removed":
+			removed = i
+		}
+	}
+	for _, row := range s.rows {
+		var cells []*htmlCell
+		for _, index := range indexes {
+			cells = append(cells, newHtmlCell(row[index]))
+		}
+		out.Rows = append(out.Rows, cells)
+		report.Total++
+		switch {
+		case removed >= 0 && row[removed] == true:
+			out.Removed++
+			report.Removed++
+		case protected < 0 || row[protected] != true:
+			out.Removable++
+			report.Removable++
+		}
+		if date, ok := rowDate(row); ok {
+			bucket := bucketOf(time.Since(date))
+			out.Histogram[bucket].Count++
+			report.Histogram[bucket].Count++
+		}
+	}
+	return out
+}
+
+func newHtmlCell(v any) *htmlCell {
+	cell := &htmlCell{Text: FormatCell(v), Sort: strings.ToLower(FormatCell(v))}
+	switch value := v.(type) {
+	case time.Time:
+		cell.Sort = fmt.Sprintf("%020d", value.Unix())
+		cell.Title = value.Format(time.RFC3339)
+	case nil:
+	default:
+		if rv := reflect.ValueOf(value); rv.CanInt() {
+			cell.Sort = fmt.Sprintf("%020d", rv.Int()+(1<<62))
+		}
+	}
+	return cell
+}
+
+// rowDate returns the first date of the row, e.g. the last commit date of a ref, used to place it in the histogram.
+func rowDate(row []any) (time.Time, bool) {
+	for _, value := range row {
+		if date, ok := value.(time.Time); ok {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func newHistogram() []*htmlBucket {
+	out := make([]*htmlBucket, len(histogramBuckets))
+	for i, bucket := range histogramBuckets {
+		out[i] = &htmlBucket{Label: bucket.label}
+	}
+	return out
+}
+
+func bucketOf(age time.Duration) int {
+	for i, bucket := range histogramBuckets {
+		if age < bucket.upTo {
+			return i
+		}
+	}
+	return len(histogramBuckets) - 1
+}
+
+func sectionHistograms(sections []*htmlSection) [][]*htmlBucket {
+	var out [][]*htmlBucket
+	for _, s := range sections {
+		out = append(out, s.Histogram)
+	}
+	return out
+}
+
+// scale sets the bar width of the buckets relative to the largest one.
+func scale(histogram []*htmlBucket) {
+	var largest int
+	for _, bucket := range histogram {
+		if bucket.Count > largest {
+			largest = bucket.Count
+		}
+	}
+	for _, bucket := range histogram {
+		if largest > 0 {
+			bucket.Percent = bucket.Count * 100 / largest
+		}
+	}
+}
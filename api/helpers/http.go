@@ -4,45 +4,79 @@ import (
 	"context"
 	"fmt"
 	"golang.org/x/oauth2"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type GitHubRoundTripper struct {
 	OauthClient *http.Client
+	transport   http.RoundTripper
 }
 
-func NewGitHubRoundTripper(ctx context.Context, token string) (*GitHubRoundTripper, error) {
+func NewGitHubRoundTripper(ctx context.Context, ts oauth2.TokenSource) (*GitHubRoundTripper, error) {
 	inst := new(GitHubRoundTripper)
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
 	inst.OauthClient = oauth2.NewClient(ctx, ts)
+	inst.transport = inst.OauthClient.Transport
 	inst.OauthClient.Transport = inst
 	return inst, nil
 }
 
+// RoundTrip waits for the rate limits to reset before retrying the rate limited requests, i.e. the requests answered
+// with a 403 or 429 and either a 'Retry-After' header (secondary rate limits) or no remaining requests.
 func (g *GitHubRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
-	resp, err := g.OauthClient.Do(r)
-	limit := resp.Header.Get("X-RateLimit-Limit")
-	used := resp.Header.Get("X-RateLimit-Used")
-	remaining := resp.Header.Get("X-RateLimit-Remaining")
-	reset := resp.Header.Get("X-RateLimit-Reset")
-	// convert from timestamp int string format (reset) to time.Duration
-	resetTime, err := time.Parse("2006-01-02T15:04:05-0700", reset)
+	resp, err := g.transport.RoundTrip(r)
 	if err != nil {
-		return resp, fmt.Errorf("unable to parse 'X-RateLimit-Reset' header. error: %v", err)
+		return resp, err
+	}
+	if limit := resp.Header.Get("X-RateLimit-Limit"); len(limit) != 0 {
+		log.Printf("GitHub API usage limit statistics: [%v/%v]...", resp.Header.Get("X-RateLimit-Used"), limit)
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return resp, nil
 	}
 
-	log.Printf("GitHub API usage limit statistics: [%v/%v]...", used, limit)
+	var wait time.Duration
+	if retryAfter := resp.Header.Get("Retry-After"); len(retryAfter) != 0 {
+		seconds, err := strconv.ParseInt(retryAfter, 10, 64)
+		if err != nil {
+			return resp, fmt.Errorf("unable to parse 'Retry-After' header. error: %v", err)
+		}
+		wait = time.Duration(seconds) * time.Second
+		log.Printf("GitHub API secondary rate limit exceeded... Retrying after: %v...", wait)
+	} else if reset := resp.Header.Get("X-RateLimit-Reset"); len(reset) != 0 && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		// convert from unix timestamp string format (reset) to time.Time
+		epoch, err := strconv.ParseInt(reset, 10, 64)
+		if err != nil {
+			return resp, fmt.Errorf("unable to parse 'X-RateLimit-Reset' header. error: %v", err)
+		}
+		resetTime := time.Unix(epoch, 0)
+		wait = time.Until(resetTime)
+		log.Printf("GitHub API usage limits exceeded... Waiting for reset: %s...", resetTime.String())
+	} else {
+		return resp, nil
+	}
 
-	if err != nil || resp.StatusCode == http.StatusForbidden {
-		if remaining == "0" {
-			log.Printf("GitHub API usage limits exceeded... Waiting for reset: %s...", resetTime.String())
-			<-time.After(resetTime.Sub(time.Now()))
-			return g.RoundTrip(r)
+	// the request body has been consumed by the rate limited attempt
+	retry := r.Clone(r.Context())
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.GetBody == nil {
+			return resp, nil
+		}
+		if retry.Body, err = r.GetBody(); err != nil {
+			return resp, fmt.Errorf("unable to rewind the request body. error: %v", err)
 		}
 	}
-	return resp, err
+	// the connection is only reused once the response body has been drained & closed
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	select {
+	case <-time.After(wait):
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	return g.RoundTrip(retry)
 }
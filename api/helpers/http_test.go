@@ -0,0 +1,75 @@
+package helpers_test
+
+import (
+	"context"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubRoundTripper_RateLimited(t *testing.T) {
+	for name, limited := range map[string]func(w http.ResponseWriter){
+		"secondary-rate-limit": func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+		},
+		"too-many-requests": func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		"primary-rate-limit": func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "0")
+			w.WriteHeader(http.StatusForbidden)
+		},
+	} {
+		t.Run(name, func(ti *testing.T) {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(ti, err)
+				bodies = append(bodies, string(body))
+				if len(bodies) == 1 {
+					limited(w)
+					_, _ = w.Write([]byte(`{"message":"rate limited"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			rt, err := helpers.NewGitHubRoundTripper(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "XXX"}))
+			assert.NoError(ti, err)
+			resp, err := rt.OauthClient.Post(server.URL, "application/json", strings.NewReader(`{"query":"q"}`))
+			assert.NoError(ti, err)
+			defer resp.Body.Close()
+
+			assert.Equal(ti, http.StatusOK, resp.StatusCode)
+			assert.Equal(ti, []string{`{"query":"q"}`, `{"query":"q"}`}, bodies)
+		})
+	}
+}
+
+func TestGitHubRoundTripper_Forbidden(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	rt, err := helpers.NewGitHubRoundTripper(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "XXX"}))
+	assert.NoError(t, err)
+	resp, err := rt.OauthClient.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
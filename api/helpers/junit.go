@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// JUnitSerializer renders views as a JUnit XML report so CI systems display the stale entries as failed tests: one
+// test suite per repository and one failed test case per entry. Repositories without stale entries get a passing test.
+type JUnitSerializer struct {
+	// Kind of the entries: branch, tag or pr.
+	Kind string
+	// Threshold the failure type of the test cases is derived from.
+	Threshold time.Duration
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+func (j *JUnitSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if text, ok := a.(string); ok {
+		return []byte(text), nil
+	}
+
+	entries, repositories := findings(a, j.Kind, j.Threshold)
+	suites := make(map[string]*junitTestSuite)
+	report := &junitTestSuites{Name: _toolName}
+	for _, repository := range repositories {
+		suites[repository] = &junitTestSuite{Name: repository}
+		report.Suites = append(report.Suites, suites[repository])
+	}
+	for _, f := range entries {
+		suite := suites[f.repository]
+		suite.Cases = append(suite.Cases, &junitTestCase{
+			Name:      fmt.Sprintf("%v %v", f.rule, f.name),
+			ClassName: f.repository,
+			Failure:   &junitFailure{Type: f.level, Message: f.message()},
+		})
+		suite.Failures++
+	}
+	for _, suite := range report.Suites {
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, &junitTestCase{Name: "no stale entries", ClassName: suite.Name})
+		}
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
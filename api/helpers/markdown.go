@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// MarkdownSerializer renders views as a (GitHub flavoured) Markdown table, one row per ref or PR.
+type MarkdownSerializer struct {
+	Columns []string
+}
+
+func (m *MarkdownSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if s, ok := a.(string); ok {
+		return []byte(s), nil
+	}
+
+	header, rows, err := records(a, m.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []byte("_No entries._"), nil
+	}
+	var b strings.Builder
+	line := func(values []string) {
+		b.WriteString("| " + strings.Join(values, " | ") + " |\n")
+	}
+	line(header)
+	separators := make([]string, len(header))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	line(separators)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = escapeMarkdown(formatRecord(value))
+		}
+		line(cells)
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+func escapeMarkdown(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
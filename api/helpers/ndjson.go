@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NdjsonSerializer renders views as newline-delimited JSON, one object per ref or PR. Empty fields are omitted.
+type NdjsonSerializer struct {
+	Columns []string
+}
+
+func (n *NdjsonSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if s, ok := a.(string); ok {
+		return []byte(s), nil
+	}
+
+	header, rows, err := records(a, n.Columns)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, row := range rows {
+		record := make(map[string]any)
+		for i, value := range row {
+			if value != nil {
+				record[header[i]] = value
+			}
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
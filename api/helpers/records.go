@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// records flattens the view into a single list of rows. The map keys leading to the rows become the leading columns:
+// 'repository' for the innermost key and 'group' for the outer ones (e.g. the owner or the policy rule). When columns
+// are provided, only these are kept (in order).
+func records(a any, columns []string) ([]string, [][]any, error) {
+	sections := flatten(a)
+
+	var depth int
+	for _, s := range sections {
+		if len(s.rows) != 0 && len(s.keys) > depth {
+			depth = len(s.keys)
+		}
+	}
+	header := keyColumns(depth)
+	index := make(map[string]int)
+	for i, name := range header {
+		index[name] = i
+	}
+
+	var rows [][]any
+	for _, s := range sections {
+		for _, name := range s.columns {
+			if _, ok := index[name]; !ok {
+				index[name] = len(header)
+				header = append(header, name)
+			}
+		}
+		for _, row := range s.rows {
+			record := make([]any, len(header))
+			for i, key := range s.keys {
+				record[depth-len(s.keys)+i] = key
+			}
+			for i, name := range s.columns {
+				record[index[name]] = row[i]
+			}
+			rows = append(rows, record)
+		}
+	}
+	for i := range rows {
+		rows[i] = append(rows[i], make([]any, len(header)-len(rows[i]))...)
+	}
+
+	if len(columns) == 0 {
+		return header, rows, nil
+	}
+	var selected []string
+	var indexes []int
+	for _, column := range columns {
+		if i, ok := index[strings.ToLower(strings.TrimSpace(column))]; ok {
+			selected = append(selected, header[i])
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 && len(rows) != 0 {
+		return nil, nil, fmt.Errorf("none of the columns [%v] are available. Available columns are: %v",
+			strings.Join(columns, ", "), strings.Join(header, ", "))
+	}
+	for r, row := range rows {
+		record := make([]any, len(indexes))
+		for i, index := range indexes {
+			record[i] = row[index]
+		}
+		rows[r] = record
+	}
+	return selected, rows, nil
+}
+
+func keyColumns(depth int) []string {
+	var out []string
+	for i := 1; i < depth; i++ {
+		if depth > 2 {
+			out = append(out, fmt.Sprintf("group_%d", i))
+		} else {
+			out = append(out, "group")
+		}
+	}
+	if depth > 0 {
+		out = append(out, "repository")
+	}
+	return out
+}
+
+// formatRecord renders a flattened value for machine-readable outputs: dates in RFC3339, nil values as empty strings.
+func formatRecord(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	_sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	_sarifVersion = "2.1.0"
+	_toolName     = "gh-tidy"
+	_toolUri      = "https://github.com/pcanilho/gh-tidy"
+)
+
+// SarifSerializer renders views as a SARIF log that can be uploaded as code scanning results. Each entry becomes a
+// result of the 'stale-<kind>' rule, e.g. 'stale-branch'.
+type SarifSerializer struct {
+	// Kind of the entries: branch, tag or pr.
+	Kind string
+	// Threshold the level of the results is derived from.
+	Threshold time.Duration
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationUri string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []*sarifLocation  `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation   `json:"physicalLocation"`
+	LogicalLocations []*sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func (s *SarifSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if text, ok := a.(string); ok {
+		return []byte(text), nil
+	}
+
+	entries, _ := findings(a, s.Kind, s.Threshold)
+	run := &sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: _toolName, InformationUri: _toolUri, Rules: []*sarifRule{}}},
+		Results: []*sarifResult{},
+	}
+	rules := make(map[string]bool)
+	for _, f := range entries {
+		if !rules[f.rule] {
+			rules[f.rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+				Id:                   f.rule,
+				Name:                 ruleName(f.rule),
+				ShortDescription:     sarifMessage{Text: fmt.Sprintf("Stale %v", f.kind)},
+				DefaultConfiguration: sarifConfiguration{Level: warningLevel},
+			})
+		}
+		result := &sarifResult{
+			RuleId:  f.rule,
+			Level:   f.level,
+			Message: sarifMessage{Text: f.message()},
+			Locations: []*sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{Uri: f.uri}},
+				LogicalLocations: []*sarifLogicalLocation{{FullyQualifiedName: f.repository + "@" + f.name, Kind: f.kind}},
+			}},
+			PartialFingerprints: map[string]string{"staleRef/v1": fmt.Sprintf("%v:%v:%v", f.repository, f.kind, f.name)},
+			Properties:          map[string]any{"repository": f.repository},
+		}
+		if f.date != nil {
+			result.Properties["last_activity"] = f.date.Format(time.RFC3339)
+		}
+		run.Results = append(run.Results, result)
+	}
+	return json.MarshalIndent(&sarifLog{Schema: _sarifSchema, Version: _sarifVersion, Runs: []*sarifRun{run}}, "", "  ")
+}
+
+// ruleName turns the rule id into its PascalCase name, e.g. 'stale-branch' into 'StaleBranch'.
+func ruleName(id string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(id, "-") {
+		if len(part) != 0 {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
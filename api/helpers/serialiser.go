@@ -8,8 +8,15 @@ import (
 type SerializerFormat = string
 
 const (
-	JSON SerializerFormat = "json"
-	YAML                  = "yaml"
+	JSON     SerializerFormat = "json"
+	YAML                      = "yaml"
+	TABLE                     = "table"
+	CSV                       = "csv"
+	NDJSON                    = "ndjson"
+	MARKDOWN                  = "markdown"
+	HTML                      = "html"
+	SARIF                     = "sarif"
+	JUNIT                     = "junit"
 )
 
 type Serializer interface {
@@ -0,0 +1,248 @@
+package helpers_test
+
+import (
+	"encoding/json"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type actor struct {
+	Login string `json:"login,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+type ref struct {
+	Id             string     `json:"id,omitempty"`
+	Name           string     `json:"name,omitempty"`
+	LastCommitDate *time.Time `json:"last_commit_date,omitempty"`
+	Protected      bool       `json:"protected,omitempty"`
+	Author         *actor     `json:"author,omitempty"`
+	Labels         []string   `json:"labels,omitempty"`
+}
+
+func view() map[string][]*ref {
+	old, recent := time.Now().Add(-43*24*time.Hour), time.Now().Add(-5*time.Hour)
+	return map[string][]*ref{
+		"repo-b": {{Id: "R1", Name: "feature/a-rather-long-branch-name", LastCommitDate: &old, Author: &actor{Name: "Mona"}}},
+		"repo-a": {
+			{Id: "R2", Name: "main", LastCommitDate: &recent, Protected: true, Author: &actor{Login: "octocat"}, Labels: []string{"x", "y"}},
+			{Id: "R3", Name: "fix"},
+		},
+		"repo-c": nil,
+	}
+}
+
+func TestAge(t *testing.T) {
+	assert.Equal(t, "just now", helpers.Age(time.Now()))
+	assert.Equal(t, "12m ago", helpers.Age(time.Now().Add(-12*time.Minute)))
+	assert.Equal(t, "5h ago", helpers.Age(time.Now().Add(-5*time.Hour)))
+	assert.Equal(t, "43d ago", helpers.Age(time.Now().Add(-43*24*time.Hour)))
+	assert.Equal(t, "in 2d", helpers.Age(time.Now().Add(49*time.Hour)))
+}
+
+func TestTableSerializer(t *testing.T) {
+	t.Run("sections", func(ti *testing.T) {
+		content, err := (&helpers.TableSerializer{}).Serialize(view())
+		assert.NoError(ti, err)
+		assert.Equal(ti, `repo-a
+NAME  LAST_COMMIT_DATE  PROTECTED  AUTHOR   LABELS
+main  5h ago            true       octocat  x, y
+fix                     false
+
+repo-b
+NAME                               LAST_COMMIT_DATE  AUTHOR
+feature/a-rather-long-branch-name  43d ago           Mona
+
+repo-c
+(none)`, string(content))
+	})
+	t.Run("columns", func(ti *testing.T) {
+		content, err := (&helpers.TableSerializer{Columns: []string{"author", "ID"}}).Serialize(view()["repo-a"])
+		assert.NoError(ti, err)
+		assert.Equal(ti, "AUTHOR   ID\noctocat  R2\n         R3", string(content))
+	})
+	t.Run("unknown-columns", func(ti *testing.T) {
+		_, err := (&helpers.TableSerializer{Columns: []string{"size"}}).Serialize(view())
+		assert.ErrorContains(ti, err, "Available columns are: id, name, last_commit_date, protected, author, labels")
+	})
+	t.Run("width", func(ti *testing.T) {
+		content, err := (&helpers.TableSerializer{Width: 36}).Serialize(view()["repo-b"])
+		assert.NoError(ti, err)
+		assert.Equal(ti, "NAME           LAST_COMMIT_…  AUTHOR\nfeature/a-ra…  43d ago        Mona", string(content))
+	})
+	t.Run("color", func(ti *testing.T) {
+		content, err := (&helpers.TableSerializer{Color: true}).Serialize(map[string][]*ref{"repo": {{Name: "main"}}})
+		assert.NoError(ti, err)
+		assert.Equal(ti, "\x1b[1;36mrepo\x1b[0m\n\x1b[1mNAME\x1b[0m\nmain", string(content))
+	})
+	t.Run("text", func(ti *testing.T) {
+		content, err := (&helpers.TableSerializer{}).Serialize("deleted")
+		assert.NoError(ti, err)
+		assert.Equal(ti, "deleted", string(content))
+	})
+}
+
+func records() map[string]map[string][]*ref {
+	date := time.Date(2023, 8, 29, 10, 0, 0, 0, time.UTC)
+	return map[string]map[string][]*ref{
+		"octocat": {
+			"x/b": {{Id: "R1", Name: "fix", LastCommitDate: &date, Labels: []string{"a", "b"}}},
+			"x/a": {{Id: "R2", Name: "feature, \"new\"", Author: &actor{Login: "octocat"}}},
+		},
+	}
+}
+
+func TestCsvSerializer(t *testing.T) {
+	content, err := (&helpers.CsvSerializer{}).Serialize(records())
+	assert.NoError(t, err)
+	assert.Equal(t, `group,repository,id,name,last_commit_date,protected,author,labels
+octocat,x/a,R2,"feature, ""new""",,false,octocat,
+octocat,x/b,R1,fix,2023-08-29T10:00:00Z,false,,"a, b"`, string(content))
+
+	content, err = (&helpers.CsvSerializer{Columns: []string{"repository", "name"}}).Serialize(view()["repo-a"])
+	assert.NoError(t, err)
+	assert.Equal(t, "name\nmain\nfix", string(content))
+
+	_, err = (&helpers.CsvSerializer{Columns: []string{"size"}}).Serialize(records())
+	assert.ErrorContains(t, err, "Available columns are: group, repository, id")
+}
+
+func TestNdjsonSerializer(t *testing.T) {
+	content, err := (&helpers.NdjsonSerializer{Columns: []string{"repository", "name", "last_commit_date"}}).Serialize(records()["octocat"])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"feature, \"new\"","repository":"x/a"}
+{"last_commit_date":"2023-08-29T10:00:00Z","name":"fix","repository":"x/b"}`, string(content))
+}
+
+func TestMarkdownSerializer(t *testing.T) {
+	content, err := (&helpers.MarkdownSerializer{Columns: []string{"repository", "name", "labels"}}).Serialize(map[string][]*ref{
+		"x/a": {{Name: "a|b", Labels: []string{"stale"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "| repository | name | labels |\n| --- | --- | --- |\n| x/a | a\\|b | stale |", string(content))
+
+	content, err = (&helpers.MarkdownSerializer{}).Serialize(map[string][]*ref{})
+	assert.NoError(t, err)
+	assert.Equal(t, "_No entries._", string(content))
+}
+
+func TestHumanize(t *testing.T) {
+	assert.Equal(t, "0s", helpers.Humanize(0))
+	assert.Equal(t, "42s", helpers.Humanize(42*time.Second))
+	assert.Equal(t, "5h 12m", helpers.Humanize(5*time.Hour+12*time.Minute+3*time.Second))
+	assert.Equal(t, "43d", helpers.Humanize(43*24*time.Hour+10*time.Minute))
+	assert.Equal(t, "28d", helpers.Humanize(-672*time.Hour))
+}
+
+func TestTemplateSerializer(t *testing.T) {
+	s, err := helpers.NewTemplateSerializer(`{{range $repo, $refs := .}}{{upper $repo}}:{{range $refs}} {{.Name}} ({{age .LastCommitDate}}) [{{join .Labels "|"}}]{{end}}
+{{end}}{{humanize 90000000000}}`)
+	assert.NoError(t, err)
+	content, err := s.Serialize(view())
+	assert.NoError(t, err)
+	assert.Equal(t, `REPO-A: main (5h ago) [x|y] fix () []
+REPO-B: feature/a-rather-long-branch-name (43d ago) []
+REPO-C:
+1m 30s`, string(content))
+
+	_, err = helpers.NewTemplateSerializer(`{{.Name`)
+	assert.ErrorContains(t, err, "invalid output template")
+
+	s, err = helpers.NewTemplateSerializer(`{{join .Name ","}}`)
+	assert.NoError(t, err)
+	_, err = s.Serialize(ref{Name: "main"})
+	assert.ErrorContains(t, err, "join: unsupported value type [string]")
+
+	content, err = s.Serialize("Restored [entries=[]]")
+	assert.NoError(t, err)
+	assert.Equal(t, "Restored [entries=[]]", string(content))
+}
+
+func TestHtmlSerializer(t *testing.T) {
+	content, err := (&helpers.HtmlSerializer{Title: "tidy stale branches"}).Serialize(view())
+	assert.NoError(t, err)
+	report := string(content)
+	assert.Contains(t, report, "<code>tidy stale branches</code>")
+	assert.Contains(t, report, "<strong>3</strong>stale entries")
+	assert.Contains(t, report, "<strong>2</strong>would be removed")
+	assert.Contains(t, report, "<h2>repo-a</h2>")
+	assert.Contains(t, report, "2 stale entries, 1 would be removed")
+	assert.Contains(t, report, `<span class="label">1-3 months</span><span class="bar" style="width: 100%"></span><span>1</span>`)
+	assert.Contains(t, report, "<th>last_commit_date</th>")
+	assert.Contains(t, report, ">43d ago</td>")
+	assert.NotContains(t, report, "<th>id</th>")
+
+	type removable struct {
+		Name    string `json:"name,omitempty"`
+		Removed bool   `json:"removed,omitempty"`
+	}
+	content, err = (&helpers.HtmlSerializer{Columns: []string{"name"}}).Serialize(map[string][]*removable{"x/a": {{Name: "<b>", Removed: true}, {Name: "c"}}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "<strong>1</strong>removed")
+	assert.Contains(t, string(content), "<strong>1</strong>would be removed")
+	assert.Contains(t, string(content), "2 stale entries, 1 removed, 1 would be removed")
+	assert.Contains(t, string(content), `<td data-sort="&lt;b&gt;">&lt;b&gt;</td>`)
+}
+
+func TestSarifSerializer(t *testing.T) {
+	content, err := (&helpers.SarifSerializer{Kind: "branch", Threshold: 7 * 24 * time.Hour}).Serialize(view())
+	assert.NoError(t, err)
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						Id   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleId    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							Uri string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				PartialFingerprints map[string]string `json:"partialFingerprints"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(content, &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, "stale-branch", log.Runs[0].Tool.Driver.Rules[0].Id)
+	assert.Equal(t, "StaleBranch", log.Runs[0].Tool.Driver.Rules[0].Name)
+
+	results := log.Runs[0].Results
+	assert.Len(t, results, 3)
+	assert.Equal(t, []string{"note", "warning", "error"}, []string{results[0].Level, results[1].Level, results[2].Level})
+	assert.Equal(t, "refs/heads/main", results[0].Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Equal(t, "repo-b:branch:feature/a-rather-long-branch-name", results[2].PartialFingerprints["staleRef/v1"])
+}
+
+func TestJUnitSerializer(t *testing.T) {
+	date := time.Now().Add(-30 * 24 * time.Hour)
+	content, err := (&helpers.JUnitSerializer{Kind: "tag", Threshold: 7 * 24 * time.Hour}).Serialize(map[string][]*ref{
+		"x/a": {{Name: "v1.0.0", LastCommitDate: &date}},
+		"x/b": nil,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="gh-tidy" tests="2" failures="1">
+  <testsuite name="x/a" tests="1" failures="1">
+    <testcase name="stale-tag v1.0.0" classname="x/a">
+      <failure type="error" message="The tag [v1.0.0] of [x/a] is stale: last activity 30d ago (`+date.Format(time.RFC3339)+`)"></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="x/b" tests="1" failures="0">
+    <testcase name="no stale entries" classname="x/b"></testcase>
+  </testsuite>
+</testsuites>`, string(content))
+}
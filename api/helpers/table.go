@@ -0,0 +1,244 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	_bold  = "\x1b[1m"
+	_title = "\x1b[1;36m"
+	_dim   = "\x1b[2m"
+	_reset = "\x1b[0m"
+
+	_columnGap     = 2
+	_minColumnSize = 8
+)
+
+// TableSerializer renders views as aligned columns, one section per repository. Dates are rendered relative to now.
+type TableSerializer struct {
+	// Columns restricts the rendered columns (in order). All non-empty columns but the ids are rendered by default.
+	Columns []string
+	// Width is the maximum line width. Columns are truncated to fit it unless 0.
+	Width int
+	// Color highlights the section titles & headers with ANSI escape codes.
+	Color bool
+}
+
+// NewTableSerializer creates a table serializer fitting the width of the terminal stdout is attached to. Colors are
+// enabled when stdout is a terminal unless the NO_COLOR environment variable is set.
+func NewTableSerializer(columns ...string) *TableSerializer {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &TableSerializer{
+		Columns: columns,
+		Width:   TerminalWidth(os.Stdout),
+		Color:   IsTerminal(os.Stdout) && !noColor,
+	}
+}
+
+func (t *TableSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if s, ok := a.(string); ok {
+		return []byte(s), nil
+	}
+
+	sections := flatten(a)
+	if err := checkColumns(sections, t.Columns); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if len(s.path) != 0 {
+			b.WriteString(t.paint(_title, strings.Join(s.path, " / ")) + "\n")
+		}
+		for _, kv := range s.meta {
+			if value := FormatCell(kv[1]); len(value) != 0 {
+				fmt.Fprintf(&b, "%v: %v\n", kv[0], value)
+			}
+		}
+		if s.columns == nil {
+			continue
+		}
+		if len(s.rows) == 0 {
+			b.WriteString(t.paint(_dim, "(none)") + "\n")
+			continue
+		}
+		t.render(&b, s)
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+func (t *TableSerializer) render(b *strings.Builder, s *section) {
+	indexes := selectColumns(s, t.Columns)
+	header := make([]string, len(indexes))
+	sizes := make([]int, len(indexes))
+	cells := make([][]string, len(s.rows))
+	for i, index := range indexes {
+		header[i] = strings.ToUpper(s.columns[index])
+		sizes[i] = len([]rune(header[i]))
+	}
+	for r, row := range s.rows {
+		cells[r] = make([]string, len(indexes))
+		for i, index := range indexes {
+			cells[r][i] = strings.ReplaceAll(FormatCell(row[index]), "\n", " ")
+			if size := len([]rune(cells[r][i])); size > sizes[i] {
+				sizes[i] = size
+			}
+		}
+	}
+	fit(sizes, t.Width)
+
+	line := func(values []string, color string) {
+		var parts []string
+		for i, value := range values {
+			value = truncate(value, sizes[i])
+			if i < len(values)-1 {
+				value += strings.Repeat(" ", sizes[i]-len([]rune(value)))
+			}
+			parts = append(parts, t.paint(color, value))
+		}
+		b.WriteString(strings.TrimRight(strings.Join(parts, strings.Repeat(" ", _columnGap)), " ") + "\n")
+	}
+	line(header, _bold)
+	for _, row := range cells {
+		line(row, "")
+	}
+}
+
+func (t *TableSerializer) paint(color, value string) string {
+	if !t.Color || len(color) == 0 || len(value) == 0 {
+		return value
+	}
+	return color + value + _reset
+}
+
+// FormatCell renders a flattened value: dates relative to now, nil values as empty strings.
+func FormatCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return Age(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// Age renders the time elapsed since t, e.g. '43d ago'.
+func Age(t time.Time) string {
+	d := time.Since(t)
+	suffix := " ago"
+	prefix := ""
+	if d < 0 {
+		d, prefix, suffix = -d, "in ", ""
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%v%dm%v", prefix, int(d.Minutes()), suffix)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%v%dh%v", prefix, int(d.Hours()), suffix)
+	}
+	return fmt.Sprintf("%v%dd%v", prefix, int(d.Hours()/24), suffix)
+}
+
+// selectColumns returns the indexes of the requested columns found in the section or, if none was requested, the
+// indexes of its columns holding at least one non-zero value other than the ids.
+func selectColumns(s *section, columns []string) []int {
+	var indexes []int
+	if len(columns) != 0 {
+		for _, column := range columns {
+			for i, name := range s.columns {
+				if strings.EqualFold(name, strings.TrimSpace(column)) {
+					indexes = append(indexes, i)
+				}
+			}
+		}
+		return indexes
+	}
+
+	for i, name := range s.columns {
+		if strings.EqualFold(name, "id") && len(s.columns) > 1 {
+			continue
+		}
+		for _, row := range s.rows {
+			if row[i] != nil && !reflect.ValueOf(row[i]).IsZero() {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// checkColumns ensures at least one of the requested columns is available in the flattened view.
+func checkColumns(sections []*section, columns []string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	var available []string
+	seen := make(map[string]bool)
+	for _, s := range sections {
+		if len(s.rows) == 0 {
+			continue
+		}
+		if len(selectColumns(s, columns)) != 0 {
+			return nil
+		}
+		for _, name := range s.columns {
+			if !seen[name] {
+				seen[name] = true
+				available = append(available, name)
+			}
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+	return fmt.Errorf("none of the columns [%v] are available. Available columns are: %v",
+		strings.Join(columns, ", "), strings.Join(available, ", "))
+}
+
+// fit shrinks the widest columns until the line fits the width (if any). Columns are never shrunk below
+// _minColumnSize.
+func fit(sizes []int, width int) {
+	if width <= 0 {
+		return
+	}
+	total := func() int {
+		sum := _columnGap * (len(sizes) - 1)
+		for _, size := range sizes {
+			sum += size
+		}
+		return sum
+	}
+	for total() > width {
+		widest := 0
+		for i, size := range sizes {
+			if size > sizes[widest] {
+				widest = i
+			}
+		}
+		if sizes[widest] <= _minColumnSize {
+			return
+		}
+		sizes[widest]--
+	}
+}
+
+func truncate(value string, size int) string {
+	runes := []rune(value)
+	if len(runes) <= size {
+		return value
+	}
+	return string(runes[:size-1]) + "…"
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateFuncs are the functions available to output templates.
+var TemplateFuncs = template.FuncMap{
+	"age":      templateAge,
+	"humanize": Humanize,
+	"join":     templateJoin,
+	"upper":    strings.ToUpper,
+}
+
+// TemplateSerializer renders views through a text/template.
+type TemplateSerializer struct {
+	tpl *template.Template
+}
+
+// NewTemplateSerializer parses the template. See TemplateFuncs for the available functions.
+func NewTemplateSerializer(text string) (*TemplateSerializer, error) {
+	tpl, err := template.New("output").Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template. error: %v", err)
+	}
+	return &TemplateSerializer{tpl: tpl}, nil
+}
+
+func (t *TemplateSerializer) Serialize(a any) ([]byte, error) {
+	if a == nil {
+		return []byte(""), nil
+	}
+	if s, ok := a.(string); ok {
+		return []byte(s), nil
+	}
+	var buf bytes.Buffer
+	if err := t.tpl.Execute(&buf, a); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Humanize renders the duration with its two most significant units, e.g. '43d 2h'.
+func Humanize(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	values := []time.Duration{d / (24 * time.Hour), d % (24 * time.Hour) / time.Hour, d % time.Hour / time.Minute, d % time.Minute / time.Second}
+	units := []string{"d", "h", "m", "s"}
+
+	var parts []string
+	for i, value := range values {
+		if value == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d%v", value, units[i]))
+		if i+1 < len(values) && values[i+1] != 0 {
+			parts = append(parts, fmt.Sprintf("%d%v", values[i+1], units[i+1]))
+		}
+		break
+	}
+	if len(parts) == 0 {
+		return "0s"
+	}
+	return strings.Join(parts, " ")
+}
+
+// templateAge renders the time (or time pointer) relative to now. Nil & zero times are rendered as empty strings.
+func templateAge(v any) (string, error) {
+	switch t := v.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "", nil
+		}
+		return Age(t), nil
+	case *time.Time:
+		if t == nil || t.IsZero() {
+			return "", nil
+		}
+		return Age(*t), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("age: unsupported value type [%T]", v)
+}
+
+// templateJoin joins the elements of any list, e.g. {{join .Labels ", "}}.
+func templateJoin(values any, sep string) (string, error) {
+	v := indirect(reflect.ValueOf(values))
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: unsupported value type [%T]", values)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
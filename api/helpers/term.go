@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"os"
+	"strconv"
+)
+
+// IsTerminal reports whether the file is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// TerminalWidth returns the width of the terminal the file is attached to, as overridden by the COLUMNS environment
+// variable. It returns 0 when the width is unknown.
+func TerminalWidth(f *os.File) int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if !IsTerminal(f) {
+		return 0
+	}
+	return terminalWidth(f)
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package helpers
+
+import "os"
+
+func terminalWidth(_ *os.File) int {
+	return 0
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin
+
+package helpers
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	rows, cols, x, y uint16
+}
+
+func terminalWidth(f *os.File) int {
+	var ws winsize
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws))); errno != 0 {
+		return 0
+	}
+	return int(ws.cols)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type ManifestEntryType = string
+
+const (
+	BranchManifestEntry ManifestEntryType = "branch"
+	TagManifestEntry                      = "tag"
+	PRManifestEntry                       = "pr"
+)
+
+// ManifestEntry records enough information about a removed ref or closed PR for it to be restored later.
+type ManifestEntry struct {
+	Repository string            `json:"repository" yaml:"repository"`
+	Type       ManifestEntryType `json:"type" yaml:"type"`
+	Id         string            `json:"id,omitempty" yaml:"id,omitempty"`
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Sha        string            `json:"sha,omitempty" yaml:"sha,omitempty"`
+	Number     int               `json:"number,omitempty" yaml:"number,omitempty"`
+	Timestamp  time.Time         `json:"timestamp" yaml:"timestamp"`
+}
+
+// Manifest is the backup of a destructive run. It is rewritten to its path every time new entries are recorded
+// so that an interrupted run still leaves a usable backup behind.
+type Manifest struct {
+	Entries []*ManifestEntry `json:"entries" yaml:"entries"`
+
+	path string
+	mu   sync.Mutex
+}
+
+func NewManifest(path string) *Manifest {
+	return &Manifest{path: path}
+}
+
+func ReadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %v. error: %v", path, err)
+	}
+	inst := &Manifest{path: path}
+	if err = json.Unmarshal(content, inst); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest: %v. error: %v", path, err)
+	}
+	return inst, nil
+}
+
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+func (m *Manifest) RecordRefs(repository string, refType RefType, refs ...*GitHubRef) error {
+	entryType := BranchManifestEntry
+	if refType == TagRefType {
+		entryType = TagManifestEntry
+	}
+	now := time.Now()
+	var entries []*ManifestEntry
+	for _, ref := range refs {
+		entries = append(entries, &ManifestEntry{
+			Repository: repository,
+			Type:       entryType,
+			Id:         ref.Id,
+			Name:       ref.Name,
+			Sha:        ref.Sha,
+			Timestamp:  now,
+		})
+	}
+	return m.record(entries...)
+}
+
+func (m *Manifest) RecordPRs(repository string, prs ...*GitHubPR) error {
+	now := time.Now()
+	var entries []*ManifestEntry
+	for _, pr := range prs {
+		entries = append(entries, &ManifestEntry{
+			Repository: repository,
+			Type:       PRManifestEntry,
+			Id:         pr.Id,
+			Name:       pr.Source,
+			Number:     pr.Number,
+			Timestamp:  now,
+		})
+	}
+	return m.record(entries...)
+}
+
+func (m *Manifest) record(entries ...*ManifestEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Entries = append(m.Entries, entries...)
+	content, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(m.path, content, 0o644); err != nil {
+		return fmt.Errorf("unable to write manifest: %v. error: %v", m.path, err)
+	}
+	return nil
+}
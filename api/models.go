@@ -1,19 +1,169 @@
 package api
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+type ActivitySource = string
+
+const (
+	CommitActivitySource  ActivitySource = "commit"
+	UpdatedActivitySource                = "updated"
+	CommentActivitySource                = "comment"
+	ReviewActivitySource                 = "review"
+	MaxActivitySource                    = "max"
+)
 
 type GitHubRef struct {
 	Id             string     `json:"id,omitempty" yaml:"id,omitempty"`
 	Name           string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Sha            string     `json:"sha,omitempty" yaml:"sha,omitempty"`
 	LastCommitDate *time.Time `json:"last_commit_date,omitempty" yaml:"last_commit_date,omitempty"`
 	TagDate        *time.Time `json:"tag_date,omitempty" yaml:"tag_date"`
+	Merged         *bool      `json:"merged,omitempty" yaml:"merged,omitempty"`
+	AheadBy        *int       `json:"ahead_by,omitempty" yaml:"ahead_by,omitempty"`
+	BehindBy       *int       `json:"behind_by,omitempty" yaml:"behind_by,omitempty"`
+	Protected      bool       `json:"protected,omitempty" yaml:"protected,omitempty"`
+	ProtectedBy    string     `json:"protected_by,omitempty" yaml:"protected_by,omitempty"`
+	Released       bool       `json:"released,omitempty" yaml:"released,omitempty"`
+
+	Author    *GitHubActor `json:"author,omitempty" yaml:"author,omitempty"`
+	Committer *GitHubActor `json:"committer,omitempty" yaml:"committer,omitempty"`
+
+	PullRequests []*GitHubRefPR `json:"pull_requests,omitempty" yaml:"pull_requests,omitempty"`
+	Orphaned     string         `json:"orphaned,omitempty" yaml:"orphaned,omitempty"`
+	// Removed is set once the ref has been deleted by the run
+	Removed bool `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+// GitHubActor is the author or committer of a commit. The login is only known for GitHub accounts.
+type GitHubActor struct {
+	Login string `json:"login,omitempty" yaml:"login,omitempty"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Matches reports whether the login, name or email of the actor is one of the provided values (case-insensitive).
+func (a *GitHubActor) Matches(values ...string) bool {
+	if a == nil {
+		return false
+	}
+	for _, value := range values {
+		for _, field := range []string{a.Login, a.Name, a.Email} {
+			if len(field) != 0 && strings.EqualFold(field, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GitHubRefPR is a PR associated with a ref, i.e. opened from it.
+type GitHubRefPR struct {
+	Id                string    `json:"id,omitempty" yaml:"id,omitempty"`
+	Number            int       `json:"number,omitempty" yaml:"number,omitempty"`
+	State             string    `json:"state,omitempty" yaml:"state,omitempty"`
+	Url               string    `json:"url,omitempty" yaml:"url,omitempty"`
+	Author            string    `json:"author,omitempty" yaml:"author,omitempty"`
+	IsCrossRepository bool      `json:"is_cross_repository,omitempty" yaml:"is_cross_repository,omitempty"`
+	CreatedAt         time.Time `json:"created_at" yaml:"created_at"`
 }
 
 type GitHubPR struct {
 	Source         string    `json:"source,omitempty" yaml:"source,omitempty"`
 	Target         string    `json:"target,omitempty" yaml:"target,omitempty"`
+	HeadSha        string    `json:"head_sha,omitempty" yaml:"head_sha,omitempty"`
+	State          string    `json:"state,omitempty" yaml:"state,omitempty"`
 	LastCommitDate time.Time `json:"last_commit_date" yaml:"last_commit_date"`
 	Id             string    `json:"id,omitempty" yaml:"id,omitempty"`
 	Number         int       `json:"number,omitempty" yaml:"number,omitempty"`
 	Url            string    `json:"url,omitempty" yaml:"url,omitempty"`
+
+	Author          string               `json:"author,omitempty" yaml:"author,omitempty"`
+	Draft           bool                 `json:"draft,omitempty" yaml:"draft,omitempty"`
+	CrossRepository bool                 `json:"cross_repository,omitempty" yaml:"cross_repository,omitempty"`
+	ReviewDecision  string               `json:"review_decision,omitempty" yaml:"review_decision,omitempty"`
+	Mergeable       string               `json:"mergeable,omitempty" yaml:"mergeable,omitempty"`
+	CreatedAt       time.Time            `json:"created_at" yaml:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at" yaml:"updated_at"`
+	Labels          []string             `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LastCommentDate *time.Time           `json:"last_comment_date,omitempty" yaml:"last_comment_date,omitempty"`
+	LastReviewDate  *time.Time           `json:"last_review_date,omitempty" yaml:"last_review_date,omitempty"`
+	LabeledAt       map[string]time.Time `json:"labeled_at,omitempty" yaml:"labeled_at,omitempty"`
+	Lifecycle       string               `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	// Removed is set once the PR has been closed by the run
+	Removed bool `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+type GitHubRepository struct {
+	Id         string   `json:"id,omitempty" yaml:"id,omitempty"`
+	Owner      string   `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Name       string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Archived   bool     `json:"archived,omitempty" yaml:"archived,omitempty"`
+	Fork       bool     `json:"fork,omitempty" yaml:"fork,omitempty"`
+	Visibility string   `json:"visibility,omitempty" yaml:"visibility,omitempty"`
+	Topics     []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+}
+
+// HasOpenPR reports whether any of the PRs associated with the ref is open.
+func (r *GitHubRef) HasOpenPR() bool {
+	for _, pr := range r.PullRequests {
+		if pr.State == "OPEN" {
+			return true
+		}
+	}
+	return false
+}
+
+// LabeledSince returns the date the PR was last labelled with the provided label, compared case-insensitively. When the
+// labelling event is unknown (e.g. it is older than the fetched timeline), the PR is considered labelled just now so
+// that it is never unmarked nor closed without knowing for how long it has been labelled.
+func (pr *GitHubPR) LabeledSince(label string) time.Time {
+	for name, at := range pr.LabeledAt {
+		if strings.EqualFold(name, label) {
+			return at
+		}
+	}
+	return time.Now()
+}
+
+// LastActivity returns the date of the last PR activity according to the provided source. PRs without any activity
+// of the requested kind (e.g. no commits, comments or reviews) fall back to their creation date.
+func (pr *GitHubPR) LastActivity(source ActivitySource) time.Time {
+	var candidates []time.Time
+	switch source {
+	case UpdatedActivitySource:
+		candidates = append(candidates, pr.UpdatedAt)
+	case CommentActivitySource:
+		if pr.LastCommentDate != nil {
+			candidates = append(candidates, *pr.LastCommentDate)
+		}
+	case ReviewActivitySource:
+		if pr.LastReviewDate != nil {
+			candidates = append(candidates, *pr.LastReviewDate)
+		}
+	case MaxActivitySource:
+		candidates = append(candidates, pr.LastCommitDate, pr.UpdatedAt)
+		if pr.LastCommentDate != nil {
+			candidates = append(candidates, *pr.LastCommentDate)
+		}
+		if pr.LastReviewDate != nil {
+			candidates = append(candidates, *pr.LastReviewDate)
+		}
+	default:
+		candidates = append(candidates, pr.LastCommitDate)
+	}
+
+	last := pr.CreatedAt
+	var found bool
+	for _, candidate := range candidates {
+		if candidate.IsZero() {
+			continue
+		}
+		if !found || candidate.After(last) {
+			last, found = candidate, true
+		}
+	}
+	return last
 }
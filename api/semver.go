@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version (https://semver.org) parsed out of a tag name. Build metadata is ignored.
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseSemver parses the provided tag name, optionally prefixed by 'v', as a semantic version.
+// Partial versions such as 'v1' or 'v1.2' are accepted with their missing components set to zero.
+func ParseSemver(name string) (*Semver, error) {
+	version := strings.TrimPrefix(strings.TrimPrefix(name, "v"), "V")
+	if i := strings.Index(version, "+"); i != -1 {
+		version = version[:i]
+	}
+
+	out := new(Semver)
+	if i := strings.Index(version, "-"); i != -1 {
+		version, out.Prerelease = version[:i], version[i+1:]
+		if len(out.Prerelease) == 0 {
+			return nil, fmt.Errorf("the tag [%v] has an empty pre-release", name)
+		}
+	}
+
+	components := strings.Split(version, ".")
+	if len(components) > 3 {
+		return nil, fmt.Errorf("the tag [%v] is not a semantic version", name)
+	}
+	for i, component := range components {
+		value, err := strconv.Atoi(component)
+		if err != nil || value < 0 || (len(component) > 1 && component[0] == '0') {
+			return nil, fmt.Errorf("the tag [%v] is not a semantic version", name)
+		}
+		switch i {
+		case 0:
+			out.Major = value
+		case 1:
+			out.Minor = value
+		case 2:
+			out.Patch = value
+		}
+	}
+	return out, nil
+}
+
+func (v *Semver) String() string {
+	if len(v.Prerelease) != 0 {
+		return fmt.Sprintf("%d.%d.%d-%v", v.Major, v.Minor, v.Patch, v.Prerelease)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func (v *Semver) IsPrerelease() bool {
+	return len(v.Prerelease) != 0
+}
+
+// Compare returns -1, 0 or 1 when the version has a lower, equal or higher precedence than the provided one.
+func (v *Semver) Compare(o *Semver) int {
+	for _, diff := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if diff != 0 {
+			return sign(diff)
+		}
+	}
+
+	// a pre-release has a lower precedence than its associated normal version
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+// compareIdentifier compares pre-release identifiers: numeric ones numerically and with a lower precedence than
+// alphanumeric ones, which are compared lexically.
+func compareIdentifier(a, b string) int {
+	na, aErr := strconv.Atoi(a)
+	nb, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return sign(na - nb)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/spf13/cobra"
+	"sync"
+)
+
+var (
+	policyPath string
+)
+
+var applyCmd = &cobra.Command{
+	Use: "apply",
+	Example: `$ gh tidy apply -c tidy.yaml
+$ gh tidy apply plan.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 || (len(args) == 1 && len(policyPath) != 0) {
+			return fmt.Errorf("either one <plan> or the [config] flag can be provided")
+		}
+		if len(args) == 1 {
+			p, err := readPlan(args[0])
+			if err != nil {
+				return err
+			}
+			// the plan is emitted even when some items failed, so their status & reason are reported
+			out = p
+			if err = applyPlan(cmd.Context(), p); err != nil {
+				return errors.Join(emit(), err)
+			}
+			return nil
+		}
+
+		if len(policyPath) == 0 {
+			return fmt.Errorf("a <plan> or a policy file provided through the [config] flag is required")
+		}
+
+		p, err := readPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+
+		view := make(map[string]any)
+		var pending removals
+		for _, rule := range p.Rules {
+			ruleView, err := applyRule(cmd.Context(), rule, nil, &pending)
+			if err != nil {
+				return fmt.Errorf("unable to apply rule [%v]. error: %v", rule.Name, err)
+			}
+			view[rule.Name] = ruleView
+		}
+		out = view
+		return pending.carryOut(cmd.Context())
+	},
+}
+
+// applyRule runs the stale pipeline of the rule against each of its repositories and adds the confirmed removals of
+// the rule action to the pending ones. When a plan is provided, the rule action is recorded into it instead.
+func applyRule(ctx context.Context, rule *policyRule, planned *plan, pending *removals) (any, error) {
+	opts := rule.options()
+	targets, err := rule.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	switch rule.Kind {
+	case branchesPolicyKind, tagsPolicyKind:
+		refType, collect := api.BranchRefType, staleBranches
+		if rule.Kind == tagsPolicyKind {
+			refType, collect = api.TagRefType, staleTags
+		}
+
+		view := make(map[string][]*api.GitHubRef)
+		err = forEachRepository(targets, func(target *repository) error {
+			rfs, err := collect(ctx, target.owner, target.name, opts)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = rfs
+			return nil
+		})
+		if err != nil || rule.Action != deletePolicyAction {
+			return view, err
+		}
+
+		for _, target := range targets {
+			rfs := view[target.String()]
+			if planned != nil {
+				planned.addRefs(rule, target, refType, rule.Action, deletableRefs(rfs, opts.includeProtected))
+				continue
+			}
+			pending.addRefs(target, refType, confirmRefs(target.name, refType, rfs, opts))
+		}
+		return view, nil
+	case prsPolicyKind:
+		view := make(map[string][]*api.GitHubPR)
+		err = forEachRepository(targets, func(target *repository) error {
+			prs, err := stalePRs(ctx, target.owner, target.name, opts)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = prs
+			return nil
+		})
+		if err != nil || rule.Action != closePolicyAction {
+			return view, err
+		}
+
+		for _, target := range targets {
+			prs := view[target.String()]
+			if planned != nil {
+				planned.addPRs(rule, target, openPRs(prs))
+				if opts.deleteBranch {
+					brs, err := headBranches(ctx, target.owner, target.name, prs, opts)
+					if err != nil {
+						return nil, err
+					}
+					planned.addRefs(rule, target, api.BranchRefType, deletePolicyAction, brs)
+				}
+				continue
+			}
+			closing, proceed := confirmPRs(target.name, prs)
+			if !proceed {
+				continue
+			}
+			var heads []*api.GitHubRef
+			if opts.deleteBranch {
+				brs, err := headBranches(ctx, target.owner, target.name, prs, opts)
+				if err != nil {
+					return nil, err
+				}
+				heads = confirmRefs(target.name, api.BranchRefType, brs, opts)
+			}
+			pending.addPRs(target, closing, heads)
+		}
+		return view, nil
+	}
+	return nil, fmt.Errorf("the kind [%v] is not supported", rule.Kind)
+}
+
+func init() {
+	applyCmd.PersistentFlags().StringVarP(&policyPath, "config", "c", "", "The path of the policy file (yaml or json) describing the cleanup rules")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/pcanilho/gh-tidy/api"
+)
+
+const _auditSyslogTag = "gh-tidy"
+
+var (
+	auditLogPath string
+	auditSyslog  bool
+)
+
+// newAuditor creates the auditors requested through the [audit-*] flags. It returns nil when none is requested.
+func newAuditor() (api.Auditor, error) {
+	var auditors api.Auditors
+	if len(auditLogPath) != 0 {
+		auditors = append(auditors, api.NewAuditLog(auditLogPath))
+	}
+	if auditSyslog {
+		auditor, err := api.NewSyslogAuditor(_auditSyslogTag)
+		if err != nil {
+			return nil, err
+		}
+		auditors = append(auditors, auditor)
+	}
+
+	if len(auditors) == 0 {
+		return nil, nil
+	}
+	return auditors, nil
+}
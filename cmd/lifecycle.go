@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"text/template"
+	"time"
+)
+
+type prLifecycle = string
+
+const (
+	// warnPRLifecycle PRs are stale and will be commented on & labelled
+	warnPRLifecycle prLifecycle = "warn"
+	// pendingPRLifecycle PRs have been warned and are within their grace period
+	pendingPRLifecycle = "pending"
+	// unmarkPRLifecycle PRs have been warned but had new activity since, so their label will be removed
+	unmarkPRLifecycle = "unmark"
+	// closePRLifecycle PRs have been warned and their grace period elapsed without new activity
+	closePRLifecycle = "close"
+)
+
+const _defaultStaleMessage = "This pull request has been automatically marked as `{{.Label}}` because it has not had recent activity. " +
+	"It will be closed on {{.CloseDate.Format \"2006-01-02\"}} unless there is new activity."
+
+var (
+	lifecycleEnabled bool
+	staleLabel       string
+	staleMessage     string
+	gracePeriod      time.Duration
+)
+
+// lifecycleOptions configure the warn-then-close lifecycle of stale PRs.
+type lifecycleOptions struct {
+	label   string
+	message *template.Template
+	grace   time.Duration
+}
+
+func newLifecycleOptions(label, message string, grace time.Duration) (*lifecycleOptions, error) {
+	if len(label) == 0 {
+		return nil, fmt.Errorf("a stale label must be provided")
+	}
+	tpl, err := template.New("message").Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stale message template. error: %v", err)
+	}
+	return &lifecycleOptions{label: label, message: tpl, grace: grace}, nil
+}
+
+// stage evaluates the lifecycle stage of the PR. PRs that are neither stale nor labelled yield an empty stage.
+func (o *staleOptions) stage(pr *api.GitHubPR) prLifecycle {
+	if !containsFold(pr.Labels, o.lifecycle.label) {
+		if o.isStalePR(pr) {
+			return warnPRLifecycle
+		}
+		return ""
+	}
+
+	// the warning comment is posted before the label is added, so it never counts as new activity
+	labeledAt := pr.LabeledSince(o.lifecycle.label)
+	activity := pr.LastCommitDate
+	if pr.LastCommentDate != nil && pr.LastCommentDate.After(activity) {
+		activity = *pr.LastCommentDate
+	}
+	switch {
+	case activity.After(labeledAt):
+		return unmarkPRLifecycle
+	case time.Now().After(labeledAt.Add(o.lifecycle.grace)):
+		return closePRLifecycle
+	default:
+		return pendingPRLifecycle
+	}
+}
+
+func (o *lifecycleOptions) render(pr *api.GitHubPR) (string, error) {
+	var buf bytes.Buffer
+	err := o.message.Execute(&buf, map[string]any{
+		"PR":          pr,
+		"Label":       o.label,
+		"GracePeriod": o.grace,
+		"CloseDate":   time.Now().Add(o.grace),
+	})
+	return buf.String(), err
+}
+
+// applyLifecycle carries out the lifecycle stage of each of the provided PRs after confirmation and returns the PRs to
+// close, which is left to the caller. It returns false when the operation has been cancelled.
+func applyLifecycle(ctx context.Context, owner, repo string, prs []*api.GitHubPR, opts *staleOptions) ([]*api.GitHubPR, bool, error) {
+	var toClose []*api.GitHubPR
+	var actionable int
+	for _, pr := range prs {
+		if pr.Lifecycle != pendingPRLifecycle {
+			actionable++
+		}
+	}
+	if actionable == 0 {
+		return nil, true, nil
+	}
+	if !force {
+		if !helpers.Prompt(fmt.Sprintf("Process the stale lifecycle of [%d] PRs in repo [%v]?", actionable, repo)) {
+			return nil, false, nil
+		}
+	}
+
+	for _, pr := range prs {
+		switch pr.Lifecycle {
+		case warnPRLifecycle:
+			body, err := opts.lifecycle.render(pr)
+			if err != nil {
+				return nil, true, err
+			}
+			if err = ghApi.AddComment(ctx, pr.Id, body); err != nil {
+				return nil, true, err
+			}
+			if err = ghApi.AddLabels(ctx, owner, repo, pr.Number, opts.lifecycle.label); err != nil {
+				return nil, true, err
+			}
+		case unmarkPRLifecycle:
+			if err := ghApi.RemoveLabel(ctx, owner, repo, pr.Number, opts.lifecycle.label); err != nil {
+				return nil, true, err
+			}
+		case closePRLifecycle:
+			toClose = append(toClose, pr)
+		}
+	}
+	return toClose, true, nil
+}
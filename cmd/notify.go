@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/notify"
+	"net"
+	"net/smtp"
+	"os"
+	"text/template"
+)
+
+var (
+	notifier notify.Notifier
+)
+
+var (
+	notifyWebhook      string
+	notifySlack        string
+	notifySmtp         string
+	notifySmtpFrom     string
+	notifySmtpTo       []string
+	notifyTemplatePath string
+)
+
+// newNotifier creates the notifiers requested through the [notify-*] flags. It returns nil when none is requested.
+// The SMTP credentials (if any) are read from the SMTP_USERNAME & SMTP_PASSWORD environment variables.
+func newNotifier() (notify.Notifier, error) {
+	var opts []notify.Option
+	if len(notifyTemplatePath) != 0 {
+		content, err := os.ReadFile(notifyTemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read notification template: %v. error: %v", notifyTemplatePath, err)
+		}
+		tpl, err := template.New("message").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification template: %v. error: %v", notifyTemplatePath, err)
+		}
+		opts = append(opts, notify.WithTemplate(tpl))
+	}
+
+	var notifiers notify.Notifiers
+	if len(notifyWebhook) != 0 {
+		notifiers = append(notifiers, notify.NewWebhook(notifyWebhook, opts...))
+	}
+	if len(notifySlack) != 0 {
+		notifiers = append(notifiers, notify.NewSlack(notifySlack, opts...))
+	}
+	if len(notifySmtp) != 0 {
+		if len(notifySmtpFrom) == 0 || len(notifySmtpTo) == 0 {
+			return nil, fmt.Errorf("the [notify-smtp-from] & [notify-smtp-to] flags must be provided along with [notify-smtp]")
+		}
+		host, _, err := net.SplitHostPort(notifySmtp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP server address [%v]. error: %v", notifySmtp, err)
+		}
+		var auth smtp.Auth
+		if username := os.Getenv("SMTP_USERNAME"); len(username) != 0 {
+			auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		notifiers = append(notifiers, notify.NewSMTP(notifySmtp, notifySmtpFrom, notifySmtpTo, auth, opts...))
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return notifiers, nil
+}
+
+// notifyRemoval notifies the closing & deletion of the provided items in a single event before they are carried out.
+func notifyRemoval(ctx context.Context, closing, deleting []*notify.Item) error {
+	action := notify.DeleteAction
+	switch {
+	case len(closing) != 0 && len(deleting) != 0:
+		action = notify.RemoveAction
+	case len(closing) != 0:
+		action = notify.CloseAction
+	}
+	return notifyItems(ctx, action, append(closing, deleting...))
+}
+
+func notifyItems(ctx context.Context, action notify.Action, items []*notify.Item) error {
+	if notifier == nil || len(items) == 0 {
+		return nil
+	}
+	return notifier.Notify(ctx, &notify.Event{Action: action, Items: items})
+}
+
+func refItem(repository string, refType api.RefType, ref *api.GitHubRef) *notify.Item {
+	kind := notify.BranchItem
+	if refType == api.TagRefType {
+		kind = notify.TagItem
+	}
+	return &notify.Item{
+		Repository: repository,
+		Kind:       kind,
+		Name:       ref.Name,
+		Author:     refOwner(ref),
+		Date:       refDate(ref),
+	}
+}
+
+func prItem(repository string, pr *api.GitHubPR) *notify.Item {
+	date := pr.LastCommitDate
+	return &notify.Item{
+		Repository: repository,
+		Kind:       notify.PRItem,
+		Name:       pr.Source,
+		Number:     pr.Number,
+		Url:        pr.Url,
+		Author:     pr.Author,
+		Date:       &date,
+	}
+}
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"github.com/pcanilho/gh-tidy/notify"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+const _planVersion = 1
+
+type planItemStatus = string
+
+const (
+	appliedPlanItem planItemStatus = "applied"
+	refusedPlanItem                = "refused"
+	skippedPlanItem                = "skipped"
+	failedPlanItem                 = "failed"
+)
+
+var (
+	planPath string
+	// stalePlanPath is the plan file the stale commands record their removals into instead of carrying them out
+	stalePlanPath string
+)
+
+// plan is the exact set of operations discovered by 'gh tidy plan' that 'gh tidy apply <plan>' carries out.
+type plan struct {
+	Version   int         `json:"version" yaml:"version"`
+	CreatedAt time.Time   `json:"created_at" yaml:"created_at"`
+	Policy    string      `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Items     []*planItem `json:"items" yaml:"items"`
+}
+
+type planItem struct {
+	Rule       string                `json:"rule" yaml:"rule"`
+	Repository string                `json:"repository" yaml:"repository"`
+	Type       api.ManifestEntryType `json:"type" yaml:"type"`
+	Action     policyAction          `json:"action" yaml:"action"`
+	Id         string                `json:"id" yaml:"id"`
+	Name       string                `json:"name,omitempty" yaml:"name,omitempty"`
+	Sha        string                `json:"sha,omitempty" yaml:"sha,omitempty"`
+	Number     int                   `json:"number,omitempty" yaml:"number,omitempty"`
+	State      string                `json:"state,omitempty" yaml:"state,omitempty"`
+	Date       *time.Time            `json:"date,omitempty" yaml:"date,omitempty"`
+
+	Status planItemStatus `json:"status,omitempty" yaml:"status,omitempty"`
+	Reason string         `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+var planCmd = &cobra.Command{
+	Use:     "plan",
+	Example: `$ gh tidy plan -c tidy.yaml --out plan.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(policyPath) == 0 {
+			return fmt.Errorf("a policy file must be provided through the [config] flag")
+		}
+		if len(planPath) == 0 {
+			return fmt.Errorf("a plan file must be provided through the [out] flag")
+		}
+
+		p, err := readPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+
+		planned := &plan{Version: _planVersion, CreatedAt: time.Now(), Policy: policyPath}
+		for _, rule := range p.Rules {
+			if _, err = applyRule(cmd.Context(), rule, planned, nil); err != nil {
+				return fmt.Errorf("unable to plan rule [%v]. error: %v", rule.Name, err)
+			}
+		}
+
+		if err = writePlan(planned, planPath); err != nil {
+			return err
+		}
+		out = planned
+		return nil
+	},
+}
+
+func writePlan(p *plan, path string) error {
+	content, err := json.MarshalIndent(p, "", " ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("unable to write plan: %v. error: %v", path, err)
+	}
+	return nil
+}
+
+// planStaleRefs records the ref deletions the [rm] flag would carry out on the view of the stale command into the
+// [plan-out] file, so they can be reviewed and carried out later through 'gh tidy apply <plan>'.
+func planStaleRefs(cmd *cobra.Command, refType api.RefType, view map[string][]*api.GitHubRef) error {
+	opts, err := staleFlagOptions()
+	if err != nil {
+		return err
+	}
+	planned := &plan{Version: _planVersion, CreatedAt: time.Now()}
+	rule := &policyRule{Name: "stale " + cmd.Name(), Action: deletePolicyAction}
+	for _, repo := range sortedRepositories(view) {
+		planned.addRefs(rule, viewTargets[repo], refType, rule.Action, deletableRefs(view[repo], opts.includeProtected))
+	}
+	return writeStalePlan(planned)
+}
+
+// planStalePRs records the PR closings (and head branch deletions) the [rm] flag would carry out on the view of the
+// stale command into the [plan-out] file. See planStaleRefs.
+func planStalePRs(ctx context.Context, cmd *cobra.Command, view map[string][]*api.GitHubPR) error {
+	opts, err := staleFlagOptions()
+	if err != nil {
+		return err
+	}
+	planned := &plan{Version: _planVersion, CreatedAt: time.Now()}
+	rule := &policyRule{Name: "stale " + cmd.Name(), Action: closePolicyAction}
+	for _, repo := range sortedRepositories(view) {
+		prs := view[repo]
+		if opts.lifecycle != nil {
+			prs = nil
+			for _, pr := range view[repo] {
+				if pr.Lifecycle == closePRLifecycle {
+					prs = append(prs, pr)
+				}
+			}
+		}
+		target := viewTargets[repo]
+		planned.addPRs(rule, target, openPRs(prs))
+		if opts.deleteBranch {
+			brs, err := headBranches(ctx, target.owner, target.name, prs, opts)
+			if err != nil {
+				return err
+			}
+			planned.addRefs(rule, target, api.BranchRefType, deletePolicyAction, brs)
+		}
+	}
+	return writeStalePlan(planned)
+}
+
+func writeStalePlan(planned *plan) error {
+	if err := writePlan(planned, stalePlanPath); err != nil {
+		return err
+	}
+	log.Printf("plan written to: %v. Use 'gh tidy apply %v' to carry it out...", stalePlanPath, stalePlanPath)
+	return nil
+}
+
+// openPRs returns the PRs of the provided ones that are still open, i.e. the ones that can be closed.
+func openPRs(prs []*api.GitHubPR) []*api.GitHubPR {
+	var out []*api.GitHubPR
+	for _, pr := range prs {
+		if pr.State == "OPEN" {
+			out = append(out, pr)
+		}
+	}
+	return out
+}
+
+// sortedRepositories returns the repositories of the view in order.
+func sortedRepositories[T any](view map[string]T) []string {
+	out := make([]string, 0, len(view))
+	for repo := range view {
+		out = append(out, repo)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (p *plan) addRefs(rule *policyRule, target *repository, refType api.RefType, action policyAction, refs []*api.GitHubRef) {
+	entryType := api.BranchManifestEntry
+	if refType == api.TagRefType {
+		entryType = api.TagManifestEntry
+	}
+	for _, ref := range refs {
+		p.Items = append(p.Items, &planItem{
+			Rule:       rule.Name,
+			Repository: target.String(),
+			Type:       entryType,
+			Action:     action,
+			Id:         ref.Id,
+			Name:       ref.Name,
+			Sha:        ref.Sha,
+			Date:       refDate(ref),
+		})
+	}
+}
+
+func (p *plan) addPRs(rule *policyRule, target *repository, prs []*api.GitHubPR) {
+	for _, pr := range prs {
+		date := pr.LastCommitDate
+		p.Items = append(p.Items, &planItem{
+			Rule:       rule.Name,
+			Repository: target.String(),
+			Type:       api.PRManifestEntry,
+			Action:     rule.Action,
+			Id:         pr.Id,
+			Name:       pr.Source,
+			Sha:        pr.HeadSha,
+			Number:     pr.Number,
+			State:      pr.State,
+			Date:       &date,
+		})
+	}
+}
+
+func readPlan(path string) (*plan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read plan: %v. error: %v", path, err)
+	}
+	p := new(plan)
+	if err = json.Unmarshal(content, p); err != nil {
+		return nil, fmt.Errorf("unable to parse plan: %v. error: %v", path, err)
+	}
+	if p.Version != _planVersion {
+		return nil, fmt.Errorf("unsupported plan version [%v]. Supported versions are: %v", p.Version, _planVersion)
+	}
+	return p, nil
+}
+
+// applyPlan verifies every planned item against its current state and carries out the ones that did not change
+// since planning. Items whose SHA or date changed are refused. Items that cannot be carried out are marked as failed
+// along with the error while the remaining ones are still carried out; the errors are joined.
+func applyPlan(ctx context.Context, p *plan) error {
+	var refIds, prIds []string
+	for _, item := range p.Items {
+		if item.Type == api.PRManifestEntry {
+			prIds = append(prIds, item.Id)
+		} else {
+			refIds = append(refIds, item.Id)
+		}
+	}
+
+	currentRefs, err := ghApi.GetRefs(ctx, refIds...)
+	if err != nil {
+		return err
+	}
+	currentPRs, err := ghApi.GetPRs(ctx, prIds...)
+	if err != nil {
+		return err
+	}
+
+	var accepted []*planItem
+	for _, item := range p.Items {
+		if item.Type == api.PRManifestEntry {
+			item.Reason = verifyPlannedPR(item, currentPRs[item.Id])
+		} else {
+			item.Reason = verifyPlannedRef(item, currentRefs[item.Id])
+		}
+		if len(item.Reason) != 0 {
+			item.Status = refusedPlanItem
+			continue
+		}
+		accepted = append(accepted, item)
+	}
+
+	if len(accepted) == 0 {
+		return nil
+	}
+	if !force {
+		if !helpers.Prompt(fmt.Sprintf("Apply [%d] planned operations ([%d] refused)?", len(accepted), len(p.Items)-len(accepted))) {
+			for _, item := range accepted {
+				item.Status = skippedPlanItem
+			}
+			return nil
+		}
+	}
+
+	var closing, deleting []*notify.Item
+	for _, item := range accepted {
+		switch item.Type {
+		case api.PRManifestEntry:
+			closing = append(closing, prItem(item.Repository, currentPRs[item.Id]))
+		case api.TagManifestEntry:
+			deleting = append(deleting, refItem(item.Repository, api.TagRefType, currentRefs[item.Id]))
+		default:
+			deleting = append(deleting, refItem(item.Repository, api.BranchRefType, currentRefs[item.Id]))
+		}
+	}
+	if err = notifyRemoval(ctx, closing, deleting); err != nil {
+		return err
+	}
+
+	var errs error
+	for _, item := range accepted {
+		if err = applyPlanItem(ctx, item, currentRefs, currentPRs); err != nil {
+			item.Status, item.Reason = failedPlanItem, err.Error()
+			errs = errors.Join(errs, fmt.Errorf("unable to apply [%v] of [%v]. error: %v", item.Name, item.Repository, err))
+			continue
+		}
+		item.Status = appliedPlanItem
+	}
+	return errs
+}
+
+// applyPlanItem carries out the planned item and records it in the backup manifest.
+func applyPlanItem(ctx context.Context, item *planItem, refs map[string]*api.GitHubRef, prs map[string]*api.GitHubPR) error {
+	if item.Type == api.PRManifestEntry {
+		if err := ghApi.ClosePRs(ctx, item.Id); err != nil {
+			return err
+		}
+		return backupManifest().RecordPRs(item.Repository, prs[item.Id])
+	}
+
+	refType := api.BranchRefType
+	if item.Type == api.TagManifestEntry {
+		refType = api.TagRefType
+	}
+	if err := ghApi.DeleteRefs(ctx, item.Id); err != nil {
+		return err
+	}
+	return backupManifest().RecordRefs(item.Repository, refType, refs[item.Id])
+}
+
+func verifyPlannedRef(item *planItem, current *api.GitHubRef) string {
+	if current == nil {
+		return "the ref no longer exists"
+	}
+	if current.Sha != item.Sha {
+		return fmt.Sprintf("the ref SHA changed from [%v] to [%v]", item.Sha, current.Sha)
+	}
+	if !sameDate(item.Date, refDate(current)) {
+		return "the ref date changed since planning"
+	}
+	return ""
+}
+
+func verifyPlannedPR(item *planItem, current *api.GitHubPR) string {
+	if current == nil {
+		return "the PR no longer exists"
+	}
+	if current.State != item.State {
+		return fmt.Sprintf("the PR state changed from [%v] to [%v]", item.State, current.State)
+	}
+	if current.HeadSha != item.Sha {
+		return fmt.Sprintf("the PR head SHA changed from [%v] to [%v]", item.Sha, current.HeadSha)
+	}
+	if !sameDate(item.Date, &current.LastCommitDate) {
+		return "the PR last commit date changed since planning"
+	}
+	return ""
+}
+
+// refDate returns the date used to evaluate the staleness of the provided ref.
+func refDate(ref *api.GitHubRef) *time.Time {
+	if ref.TagDate != nil {
+		return ref.TagDate
+	}
+	return ref.LastCommitDate
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func init() {
+	planCmd.PersistentFlags().StringVarP(&policyPath, "config", "c", "", "The path of the policy file (yaml or json) describing the cleanup rules")
+	planCmd.PersistentFlags().StringVar(&planPath, "out", "", "The path where the plan file will be written")
+}
@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type policyKind = string
+
+const (
+	branchesPolicyKind policyKind = "branches"
+	tagsPolicyKind                = "tags"
+	prsPolicyKind                 = "prs"
+)
+
+type policyAction = string
+
+const (
+	reportPolicyAction policyAction = "report"
+	deletePolicyAction              = "delete"
+	closePolicyAction               = "close"
+)
+
+const _policyVersion = 1
+
+// policy is the declarative description of a cleanup run. Both YAML & JSON documents are supported:
+//
+//	version: 1
+//	defaults:
+//	  threshold: 720h
+//	rules:
+//	  - name: stale-feature-branches
+//	    owner: my-org
+//	    repositories: [service-a, other-org/service-b]
+//	    kind: branches
+//	    include: '^feature/'
+//	    action: delete
+//	  - name: stale-org-tags
+//	    org: my-org
+//	    filter: {topics: [release], pattern: '^service-'}
+//	    kind: tags
+//	    action: report
+type policy struct {
+	Version  int           `json:"version" yaml:"version"`
+	Defaults policyRule    `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	Rules    []*policyRule `json:"rules" yaml:"rules"`
+}
+
+type policyRule struct {
+	Name             string           `json:"name,omitempty" yaml:"name,omitempty"`
+	Owner            string           `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Repositories     []string         `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+	Org              string           `json:"org,omitempty" yaml:"org,omitempty"`
+	User             string           `json:"user,omitempty" yaml:"user,omitempty"`
+	Filter           repositoryFilter `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Kind             policyKind       `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Threshold        string           `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	Include          string           `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude          string           `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	States           []string         `json:"states,omitempty" yaml:"states,omitempty"`
+	Merged           bool             `json:"merged,omitempty" yaml:"merged,omitempty"`
+	Labels           []string         `json:"labels,omitempty" yaml:"labels,omitempty"`
+	ExcludeLabels    []string         `json:"exclude_labels,omitempty" yaml:"exclude_labels,omitempty"`
+	Authors          []string         `json:"authors,omitempty" yaml:"authors,omitempty"`
+	ExcludeAuthors   []string         `json:"exclude_authors,omitempty" yaml:"exclude_authors,omitempty"`
+	DraftOnly        bool             `json:"draft_only,omitempty" yaml:"draft_only,omitempty"`
+	ActivitySource   string           `json:"activity_source,omitempty" yaml:"activity_source,omitempty"`
+	DeleteBranch     bool             `json:"delete_branch,omitempty" yaml:"delete_branch,omitempty"`
+	IncludeProtected bool             `json:"include_protected,omitempty" yaml:"include_protected,omitempty"`
+	Action           policyAction     `json:"action,omitempty" yaml:"action,omitempty"`
+
+	KeepLatest          int    `json:"keep_latest,omitempty" yaml:"keep_latest,omitempty"`
+	KeepPer             string `json:"keep_per,omitempty" yaml:"keep_per,omitempty"`
+	KeepReleased        bool   `json:"keep_released,omitempty" yaml:"keep_released,omitempty"`
+	PrereleaseThreshold string `json:"prerelease_threshold,omitempty" yaml:"prerelease_threshold,omitempty"`
+	Sort                string `json:"sort,omitempty" yaml:"sort,omitempty"`
+
+	KeepLast   int            `json:"keep_last,omitempty" yaml:"keep_last,omitempty"`
+	Quotas     map[string]int `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+	WithOpenPR string         `json:"with_open_pr,omitempty" yaml:"with_open_pr,omitempty"`
+	PRStates   []string       `json:"pr_states,omitempty" yaml:"pr_states,omitempty"`
+
+	Base           string `json:"base,omitempty" yaml:"base,omitempty"`
+	Ahead          *int   `json:"ahead,omitempty" yaml:"ahead,omitempty"`
+	BehindMoreThan *int   `json:"behind_more_than,omitempty" yaml:"behind_more_than,omitempty"`
+}
+
+// readPolicy decodes & validates the policy file at the provided path. Unknown fields are rejected.
+func readPolicy(path string) (*policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy: %v. error: %v", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	p := new(policy)
+	if err = decoder.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse policy: %v. error: %v", path, err)
+	}
+
+	p.applyDefaults()
+	if err = p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v. error: %v", path, err)
+	}
+	return p, nil
+}
+
+func (p *policy) applyDefaults() {
+	for _, rule := range p.Rules {
+		if rule == nil {
+			continue
+		}
+		if len(rule.Owner) == 0 {
+			rule.Owner = p.Defaults.Owner
+		}
+		if len(rule.Repositories) == 0 && len(rule.Org) == 0 && len(rule.User) == 0 {
+			rule.Repositories = p.Defaults.Repositories
+			rule.Org, rule.User = p.Defaults.Org, p.Defaults.User
+			if reflect.DeepEqual(rule.Filter, repositoryFilter{}) {
+				rule.Filter = p.Defaults.Filter
+			}
+		}
+		if len(rule.Threshold) == 0 {
+			rule.Threshold = p.Defaults.Threshold
+		}
+		if len(rule.Include) == 0 {
+			rule.Include = p.Defaults.Include
+		}
+		if len(rule.Exclude) == 0 {
+			rule.Exclude = p.Defaults.Exclude
+		}
+		if len(rule.States) == 0 {
+			rule.States = p.Defaults.States
+		}
+		if len(rule.ActivitySource) == 0 {
+			rule.ActivitySource = p.Defaults.ActivitySource
+		}
+		if len(rule.Action) == 0 {
+			rule.Action = p.Defaults.Action
+		}
+		if len(rule.Action) == 0 {
+			rule.Action = reportPolicyAction
+		}
+	}
+}
+
+// validate checks the policy against its schema and reports every violation found.
+func (p *policy) validate() error {
+	var err error
+	if p.Version != _policyVersion {
+		err = errors.Join(err, fmt.Errorf("unsupported version [%v]. Supported versions are: %v", p.Version, _policyVersion))
+	}
+	if len(p.Rules) == 0 {
+		err = errors.Join(err, fmt.Errorf("at least one rule must be provided"))
+	}
+
+	names := make(map[string]bool)
+	for i, rule := range p.Rules {
+		if rule == nil {
+			err = errors.Join(err, fmt.Errorf("rules[%d]: the rule must not be empty", i))
+			continue
+		}
+		if len(rule.Name) == 0 {
+			err = errors.Join(err, fmt.Errorf("rules[%d]: a name must be provided", i))
+		} else if names[rule.Name] {
+			err = errors.Join(err, fmt.Errorf("rules[%d]: the name [%v] is not unique", i, rule.Name))
+		}
+		names[rule.Name] = true
+
+		for _, ruleErr := range rule.validate() {
+			err = errors.Join(err, fmt.Errorf("rules[%d]: %v", i, ruleErr))
+		}
+	}
+	return err
+}
+
+func (r *policyRule) validate() []error {
+	var errs []error
+	switch r.Kind {
+	case branchesPolicyKind, tagsPolicyKind:
+		if r.Action != reportPolicyAction && r.Action != deletePolicyAction {
+			errs = append(errs, fmt.Errorf("the action [%v] is not supported for [%v]. Supported values are: report, delete", r.Action, r.Kind))
+		}
+	case prsPolicyKind:
+		if r.Action != reportPolicyAction && r.Action != closePolicyAction {
+			errs = append(errs, fmt.Errorf("the action [%v] is not supported for [%v]. Supported values are: report, close", r.Action, r.Kind))
+		}
+		for _, state := range r.States {
+			switch strings.ToUpper(state) {
+			case "OPEN", "MERGED", "CLOSED":
+			default:
+				errs = append(errs, fmt.Errorf("the PR state [%v] is not supported. Supported values are: OPEN, MERGED or CLOSED", state))
+			}
+		}
+		if len(r.ActivitySource) != 0 {
+			if err := validateActivitySource(r.ActivitySource); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("the kind [%v] is not supported. Supported values are: branches, tags, prs", r.Kind))
+	}
+
+	if (r.KeepLatest != 0 || len(r.KeepPer) != 0 || r.KeepReleased || len(r.PrereleaseThreshold) != 0 || len(r.Sort) != 0) && r.Kind != tagsPolicyKind {
+		errs = append(errs, fmt.Errorf("the [keep_latest], [keep_per], [keep_released], [prerelease_threshold] & [sort] options are only supported for tags"))
+	}
+	if len(r.PrereleaseThreshold) != 0 {
+		if _, parseErr := time.ParseDuration(r.PrereleaseThreshold); parseErr != nil {
+			errs = append(errs, fmt.Errorf("invalid prerelease threshold [%v]. error: %v", r.PrereleaseThreshold, parseErr))
+		}
+	}
+	if r.Kind == tagsPolicyKind {
+		if _, err := r.tagRetention(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if (r.KeepLast != 0 || len(r.Quotas) != 0 || len(r.WithOpenPR) != 0 || len(r.PRStates) != 0) && r.Kind != branchesPolicyKind {
+		errs = append(errs, fmt.Errorf("the [keep_last], [quotas], [with_open_pr] & [pr_states] options are only supported for branches"))
+	}
+	if (len(r.Base) != 0 || r.Ahead != nil || r.BehindMoreThan != nil) && r.Kind != branchesPolicyKind {
+		errs = append(errs, fmt.Errorf("the [base], [ahead] & [behind_more_than] options are only supported for branches"))
+	}
+	if (r.Ahead != nil && *r.Ahead < 0) || (r.BehindMoreThan != nil && *r.BehindMoreThan < 0) {
+		errs = append(errs, fmt.Errorf("the [ahead] & [behind_more_than] options must not be negative"))
+	}
+	if err := validateBranchPRFilters(r.WithOpenPR, r.PRStates); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := r.branchQuotas(); err != nil {
+		errs = append(errs, err)
+	}
+	if r.Merged && r.Kind != branchesPolicyKind {
+		errs = append(errs, fmt.Errorf("the [merged] option is only supported for branches"))
+	}
+	if (len(r.Labels) != 0 || len(r.ExcludeLabels) != 0 || r.DraftOnly || len(r.ActivitySource) != 0 || r.DeleteBranch) && r.Kind != prsPolicyKind {
+		errs = append(errs, fmt.Errorf("the [labels], [exclude_labels], [draft_only], [activity_source] & [delete_branch] options are only supported for prs"))
+	}
+	if len(r.Authors) != 0 && r.Kind == tagsPolicyKind {
+		errs = append(errs, fmt.Errorf("the [authors] option is only supported for branches & prs"))
+	}
+	if len(r.ExcludeAuthors) != 0 && r.Kind != branchesPolicyKind {
+		errs = append(errs, fmt.Errorf("the [exclude_authors] option is only supported for branches"))
+	}
+	if len(r.Repositories) == 0 && len(r.Org) == 0 && len(r.User) == 0 {
+		errs = append(errs, fmt.Errorf("at least one repository or an org or user must be provided"))
+	}
+	if len(r.Org) != 0 && len(r.User) != 0 {
+		errs = append(errs, fmt.Errorf("only one of org or user can be provided"))
+	}
+	if err := r.Filter.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range r.Repositories {
+		if len(r.Owner) == 0 && !strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("the repository [%v] must use the 'owner/repository' format when no owner is set", name))
+		}
+	}
+	if len(r.Threshold) != 0 {
+		if _, parseErr := time.ParseDuration(r.Threshold); parseErr != nil {
+			errs = append(errs, fmt.Errorf("invalid threshold [%v]. error: %v", r.Threshold, parseErr))
+		}
+	}
+	for _, pattern := range []string{r.Include, r.Exclude} {
+		if _, compileErr := regexp.Compile(pattern); compileErr != nil {
+			errs = append(errs, fmt.Errorf("invalid pattern [%v]. error: %v", pattern, compileErr))
+		}
+	}
+	return errs
+}
+
+// options converts the (validated) rule into the selection rules used by the stale pipelines.
+func (r *policyRule) options() *staleOptions {
+	opts := &staleOptions{
+		threshold:        _defaultStaleThreshold,
+		states:           r.States,
+		merged:           r.Merged,
+		labels:           r.Labels,
+		excludeLabels:    r.ExcludeLabels,
+		authors:          r.Authors,
+		excludeAuthors:   r.ExcludeAuthors,
+		draftOnly:        r.DraftOnly,
+		activity:         r.ActivitySource,
+		deleteBranch:     r.DeleteBranch,
+		includeProtected: r.IncludeProtected,
+		withOpenPR:       r.WithOpenPR,
+		prStates:         r.PRStates,
+		base:             r.Base,
+		ahead:            -1,
+		behindMoreThan:   -1,
+	}
+	if r.Ahead != nil {
+		opts.ahead = *r.Ahead
+	}
+	if r.BehindMoreThan != nil {
+		opts.behindMoreThan = *r.BehindMoreThan
+	}
+	if len(r.Threshold) != 0 {
+		opts.threshold, _ = time.ParseDuration(r.Threshold)
+	}
+	if len(r.Include) != 0 {
+		opts.include = regexp.MustCompile(r.Include)
+	}
+	if len(r.Exclude) != 0 {
+		opts.exclude = regexp.MustCompile(r.Exclude)
+	}
+	if len(opts.states) == 0 {
+		opts.states = []string{"OPEN"}
+	}
+	if len(opts.activity) == 0 {
+		opts.activity = api.CommitActivitySource
+	}
+	opts.tags, _ = r.tagRetention()
+	opts.quotas, _ = r.branchQuotas()
+	return opts
+}
+
+func (r *policyRule) branchQuotas() ([]*branchQuota, error) {
+	var values []string
+	for pattern, keep := range r.Quotas {
+		values = append(values, fmt.Sprintf("%v=%d", pattern, keep))
+	}
+	return parseQuotas(r.KeepLast, values)
+}
+
+func (r *policyRule) tagRetention() (*tagRetention, error) {
+	line, sortBy := r.KeepPer, r.Sort
+	if len(line) == 0 {
+		line = minorRetentionLine
+	}
+	if len(sortBy) == 0 {
+		sortBy = nameTagSort
+	}
+	prerelease, _ := time.ParseDuration(r.PrereleaseThreshold)
+	return newTagRetention(r.KeepLatest, line, r.KeepReleased, prerelease, sortBy)
+}
+
+// targets resolves the rule repositories into their owner & name, scanning the rule org or user if provided.
+func (r *policyRule) targets(ctx context.Context) ([]*repository, error) {
+	var out []*repository
+	for _, name := range r.Repositories {
+		target := &repository{owner: r.Owner, name: name}
+		if strings.Contains(name, "/") {
+			composite := strings.SplitN(name, "/", 2)
+			target.owner, target.name = composite[0], composite[1]
+		}
+		out = append(out, target)
+	}
+
+	if login := r.Org + r.User; len(login) != 0 {
+		scanned, err := scanRepositories(ctx, login, &r.Filter)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, scanned...)
+	}
+	return out, nil
+}
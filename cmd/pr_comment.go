@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+type openPRCommentMode = string
+
+const (
+	// noOpenPRComment branches backing open PRs are deleted without notice
+	noOpenPRComment openPRCommentMode = "none"
+	// beforeOpenPRComment open PRs are commented on right before their head branch is deleted
+	beforeOpenPRComment = "before"
+	// insteadOpenPRComment open PRs are commented on and their head branch is kept until the scheduled deletion date,
+	// after which a later run deletes it
+	insteadOpenPRComment = "instead"
+)
+
+const _defaultOpenPRMessage = "The head branch `{{.Branch.Name}}` of this pull request is stale and is scheduled for deletion on " +
+	"{{.DeletionDate.Format \"2006-01-02\"}}. Deleting it will close this pull request."
+
+var (
+	openPRComment string
+	openPRMessage string
+	openPRGrace   time.Duration
+)
+
+// openPRCommentOptions configure the comments posted on the open PRs whose head branch will be deleted.
+type openPRCommentOptions struct {
+	mode    openPRCommentMode
+	message *template.Template
+	grace   time.Duration
+}
+
+// newOpenPRCommentOptions returns nil when open PRs must not be commented on.
+func newOpenPRCommentOptions(mode openPRCommentMode, message string, grace time.Duration) (*openPRCommentOptions, error) {
+	switch mode {
+	case noOpenPRComment:
+		return nil, nil
+	case beforeOpenPRComment, insteadOpenPRComment:
+	default:
+		return nil, fmt.Errorf("the open PR comment mode [%v] is not supported. Supported values are: none, before, instead", mode)
+	}
+	tpl, err := template.New("message").Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid open PR message template. error: %v", err)
+	}
+	return &openPRCommentOptions{mode: mode, message: tpl, grace: grace}, nil
+}
+
+// _scheduleMarker persists the deletion date scheduled by the 'instead' comments, so later runs neither comment again
+// nor postpone the deletion.
+var _scheduleMarker = regexp.MustCompile(`<!-- gh-tidy:scheduled-deletion (\S+) -->`)
+
+func (o *openPRCommentOptions) render(repository string, branch *api.GitHubRef, pr *api.GitHubRefPR, deletion time.Time) (string, error) {
+	var buf bytes.Buffer
+	err := o.message.Execute(&buf, map[string]any{
+		"Repository":   repository,
+		"Branch":       branch,
+		"PR":           pr,
+		"DeletionDate": deletion,
+	})
+	if err != nil || o.mode != insteadOpenPRComment {
+		return buf.String(), err
+	}
+	return fmt.Sprintf("%v\n\n<!-- gh-tidy:scheduled-deletion %v -->", buf.String(), deletion.UTC().Format(time.RFC3339)), nil
+}
+
+// schedules returns the deletion dates scheduled by the comments previously posted on the provided open PRs, if any.
+func (o *openPRCommentOptions) schedules(ctx context.Context, prs []*api.GitHubRefPR) (map[string]time.Time, error) {
+	if o.mode != insteadOpenPRComment || len(prs) == 0 {
+		return nil, nil
+	}
+	var ids []string
+	for _, pr := range prs {
+		ids = append(ids, pr.Id)
+	}
+	comments, err := ghApi.ViewerComments(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Time)
+	for id, bodies := range comments {
+		for _, body := range bodies {
+			match := _scheduleMarker.FindStringSubmatch(body)
+			if match == nil {
+				continue
+			}
+			if date, err := time.Parse(time.RFC3339, match[1]); err == nil && date.After(out[id]) {
+				out[id] = date
+			}
+		}
+	}
+	return out, nil
+}
+
+// pendingComment is a comment to post on an open PR backed by a stale branch.
+type pendingComment struct {
+	pr   *api.GitHubRefPR
+	body string
+}
+
+// confirmBranches returns the branches to delete out of the provided ones after a single confirmation, which also
+// covers the comments on the open PRs they back (if requested). The comments are returned to be posted along with the
+// deletion and nothing is returned when declined. Protected branches are skipped unless the protection override has
+// been requested.
+//
+// In 'instead' mode, the branches backing open PRs are kept until the deletion date scheduled by their first comment
+// and only deleted by a run past that date. Open PRs already commented on are not commented on again.
+func confirmBranches(ctx context.Context, target *repository, branches []*api.GitHubRef, opts *staleOptions) ([]*api.GitHubRef, []*pendingComment, error) {
+	if opts.openPRComment == nil {
+		return confirmRefs(target.name, api.BranchRefType, branches, opts), nil, nil
+	}
+
+	var deletable, backing []*api.GitHubRef
+	var open []*api.GitHubRefPR
+	for _, branch := range deletableRefs(branches, opts.includeProtected) {
+		if !branch.HasOpenPR() {
+			deletable = append(deletable, branch)
+			continue
+		}
+		backing = append(backing, branch)
+		open = append(open, openPRsOf(branch)...)
+	}
+	schedules, err := opts.openPRComment.schedules(ctx, open)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	var comments []*pendingComment
+	for _, branch := range backing {
+		if opts.openPRComment.mode != insteadOpenPRComment {
+			for _, pr := range openPRsOf(branch) {
+				body, err := opts.openPRComment.render(target.String(), branch, pr, now)
+				if err != nil {
+					return nil, nil, err
+				}
+				comments = append(comments, &pendingComment{pr: pr, body: body})
+			}
+			deletable = append(deletable, branch)
+			continue
+		}
+
+		var scheduled time.Time
+		for _, pr := range openPRsOf(branch) {
+			if date, found := schedules[pr.Id]; found && date.After(scheduled) {
+				scheduled = date
+			}
+		}
+		switch {
+		case scheduled.IsZero():
+			scheduled = now.Add(opts.openPRComment.grace)
+		case !scheduled.After(now):
+			deletable = append(deletable, branch)
+			continue
+		}
+		for _, pr := range openPRsOf(branch) {
+			if _, found := schedules[pr.Id]; found {
+				continue
+			}
+			body, err := opts.openPRComment.render(target.String(), branch, pr, scheduled)
+			if err != nil {
+				return nil, nil, err
+			}
+			comments = append(comments, &pendingComment{pr: pr, body: body})
+		}
+	}
+
+	if len(deletable) == 0 && len(comments) == 0 {
+		return nil, nil, nil
+	}
+	if !force {
+		prompt := fmt.Sprintf("Delete [%d] branches in repo [%v]?", len(deletable), target.name)
+		if len(comments) != 0 {
+			prompt = fmt.Sprintf("Delete [%d] branches & comment on [%d] open PRs in repo [%v]?", len(deletable), len(comments), target.name)
+		}
+		if !helpers.Prompt(prompt) {
+			return nil, nil, nil
+		}
+	}
+	return deletable, comments, nil
+}
+
+func openPRsOf(branch *api.GitHubRef) []*api.GitHubRefPR {
+	var out []*api.GitHubRefPR
+	for _, pr := range branch.PullRequests {
+		if pr.State == "OPEN" {
+			out = append(out, pr)
+		}
+	}
+	return out
+}
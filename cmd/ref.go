@@ -5,6 +5,7 @@ import (
 	"github.com/pcanilho/gh-tidy/api"
 	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/spf13/cobra"
+	"log"
 	"os"
 	"strings"
 )
@@ -41,14 +42,22 @@ var deleteRefCmd = &cobra.Command{
 			return err
 		}
 
-		rfs := append(brs, tgs...)
-		var toDeleteIds []string
-		for _, rf := range rfs {
-			for _, ref := range refs {
-				if rf.Name == ref {
-					toDeleteIds = append(toDeleteIds, rf.Id)
+		matching := func(rfs []*api.GitHubRef) []*api.GitHubRef {
+			var matched []*api.GitHubRef
+			for _, rf := range deletableRefs(rfs, includeProtected) {
+				for _, ref := range refs {
+					if rf.Name == ref {
+						matched = append(matched, rf)
+					}
 				}
 			}
+			return matched
+		}
+		brs, tgs = matching(brs), matching(tgs)
+
+		var toDeleteIds []string
+		for _, rf := range append(brs, tgs...) {
+			toDeleteIds = append(toDeleteIds, rf.Id)
 		}
 
 		if len(toDeleteIds) == 0 {
@@ -60,8 +69,12 @@ var deleteRefCmd = &cobra.Command{
 				os.Exit(0)
 			}
 		}
-		if err = ghApi.DeleteRefs(cmd.Context(), toDeleteIds...); err != nil {
-			return fmt.Errorf("unable to delete [refs=%v]. error: %v", refs, err)
+		var pending removals
+		target := &repository{owner: owner, name: repo}
+		pending.addRefs(target, api.BranchRefType, brs)
+		pending.addRefs(target, api.TagRefType, tgs)
+		if err = pending.carryOut(cmd.Context()); err != nil {
+			return err
 		}
 
 		out = fmt.Sprintf("Deleted [refs=%v] with [ids=%v]\n", refs, toDeleteIds)
@@ -72,3 +85,19 @@ var deleteRefCmd = &cobra.Command{
 func init() {
 	deleteRefCmd.PersistentFlags().StringArrayVar(&refs, "ref", nil, "The provided ref(s) to be deleted. Only branch or tag name or are supported")
 }
+
+// deletableRefs filters out protected refs unless the protection override has been requested.
+func deletableRefs(rfs []*api.GitHubRef, includeProtected bool) []*api.GitHubRef {
+	if includeProtected {
+		return rfs
+	}
+	var out []*api.GitHubRef
+	for _, rf := range rfs {
+		if rf.Protected {
+			log.Printf("skipping protected ref: %v (%v)...", rf.Name, rf.ProtectedBy)
+			continue
+		}
+		out = append(out, rf)
+	}
+	return out
+}
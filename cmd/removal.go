@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"github.com/pcanilho/gh-tidy/notify"
+)
+
+// removal is the confirmed removal of the PRs and/or refs of a repository.
+type removal struct {
+	target  *repository
+	prs     []*api.GitHubPR
+	refType api.RefType
+	refs    []*api.GitHubRef
+	// comments are posted on the open PRs backed by the refs right before the refs are deleted
+	comments []*pendingComment
+}
+
+// removals collects the removals confirmed across the repositories of a run, so that they are notified at once before
+// any of them is carried out.
+type removals []*removal
+
+func (r *removals) addRefs(target *repository, refType api.RefType, refs []*api.GitHubRef) {
+	if len(refs) != 0 {
+		*r = append(*r, &removal{target: target, refType: refType, refs: refs})
+	}
+}
+
+// addBranches adds the branches to delete along with the comments to post on the open PRs they back, including the
+// ones only scheduling the deletion of kept branches.
+func (r *removals) addBranches(target *repository, branches []*api.GitHubRef, comments []*pendingComment) {
+	if len(branches) != 0 || len(comments) != 0 {
+		*r = append(*r, &removal{target: target, refType: api.BranchRefType, refs: branches, comments: comments})
+	}
+}
+
+// addPRs adds the PRs to close along with their head branches, which are only deleted once all the PRs are closed.
+func (r *removals) addPRs(target *repository, prs []*api.GitHubPR, heads []*api.GitHubRef) {
+	if len(prs) != 0 || len(heads) != 0 {
+		*r = append(*r, &removal{target: target, prs: prs, refType: api.BranchRefType, refs: heads})
+	}
+}
+
+// carryOut notifies all the removals in a single event and carries them out. A failed notification aborts the run
+// while a failed removal does not prevent the remaining ones; the errors are joined.
+func (r removals) carryOut(ctx context.Context) error {
+	var closing, deleting []*notify.Item
+	for _, rm := range r {
+		for _, pr := range rm.prs {
+			closing = append(closing, prItem(rm.target.String(), pr))
+		}
+		for _, ref := range rm.refs {
+			deleting = append(deleting, refItem(rm.target.String(), rm.refType, ref))
+		}
+	}
+	if err := notifyRemoval(ctx, closing, deleting); err != nil {
+		return err
+	}
+
+	var errs error
+	for _, rm := range r {
+		if len(rm.prs) != 0 {
+			if err := closeEachPR(ctx, rm.target.String(), rm.prs); err != nil {
+				errs = errors.Join(errs, err)
+				// the head branches of PRs left open must not be deleted
+				continue
+			}
+		}
+		if err := commentEach(ctx, rm.comments); err != nil {
+			errs = errors.Join(errs, err)
+			// the refs must not be deleted without the open PRs being told
+			continue
+		}
+		errs = errors.Join(errs, deleteEachRef(ctx, rm.target.String(), rm.refType, rm.refs))
+	}
+	return errs
+}
+
+// confirmRefs returns the refs to delete out of the provided ones after confirmation, none when declined. Protected
+// refs are skipped unless the protection override has been requested.
+func confirmRefs(repo string, refType api.RefType, refs []*api.GitHubRef, opts *staleOptions) []*api.GitHubRef {
+	kind := "branches"
+	if refType == api.TagRefType {
+		kind = "tags"
+	}
+
+	refs = deletableRefs(refs, opts.includeProtected)
+	if len(refs) == 0 {
+		return nil
+	}
+	if !force {
+		if !helpers.Prompt(fmt.Sprintf("Delete [%d] %v in repo [%v]?", len(refs), kind, repo)) {
+			return nil
+		}
+	}
+	return refs
+}
+
+// confirmPRs returns the PRs to close out of the provided ones after confirmation. PRs that are no longer open are
+// skipped. It returns false when the operation has been cancelled.
+func confirmPRs(repo string, prs []*api.GitHubPR) ([]*api.GitHubPR, bool) {
+	open := openPRs(prs)
+	if len(open) == 0 {
+		return nil, true
+	}
+	if !force {
+		if !helpers.Prompt(fmt.Sprintf("Close [%d] PRs in repo [%v]?", len(open), repo)) {
+			return nil, false
+		}
+	}
+	return open, true
+}
+
+// deleteEachRef deletes the provided refs, recording each of them in the backup manifest once deleted. Refs that
+// cannot be deleted do not prevent the deletion of the remaining ones; the errors are joined.
+func deleteEachRef(ctx context.Context, repository string, refType api.RefType, refs []*api.GitHubRef) error {
+	noun := "branch"
+	if refType == api.TagRefType {
+		noun = "tag"
+	}
+
+	var errs error
+	for _, ref := range refs {
+		if err := ghApi.DeleteRefs(ctx, ref.Id); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("unable to delete %v: %v. error: %v", noun, ref.Name, err))
+			continue
+		}
+		ref.Removed = true
+		if err := backupManifest().RecordRefs(repository, refType, ref); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// commentEach posts the provided comments, stopping at the first one that cannot be posted.
+func commentEach(ctx context.Context, comments []*pendingComment) error {
+	for _, c := range comments {
+		if err := ghApi.AddComment(ctx, c.pr.Id, c.body); err != nil {
+			return fmt.Errorf("unable to comment on PR: #%v. error: %v", c.pr.Number, err)
+		}
+	}
+	return nil
+}
+
+// closeEachPR closes the provided PRs, recording each of them in the backup manifest once closed. PRs that cannot be
+// closed do not prevent the closing of the remaining ones; the errors are joined.
+func closeEachPR(ctx context.Context, repository string, prs []*api.GitHubPR) error {
+	var errs error
+	for _, pr := range prs {
+		if err := ghApi.ClosePRs(ctx, pr.Id); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("unable to close PR: #%v. error: %v", pr.Number, err))
+			continue
+		}
+		pr.Removed = true
+		if err := backupManifest().RecordPRs(repository, pr); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
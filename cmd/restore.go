@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/pcanilho/gh-tidy/api/helpers"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:     "restore",
+	Aliases: []string{"undo"},
+	Example: `$ gh tidy restore <manifest>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("only one <manifest> can be provided")
+		}
+
+		m, err := api.ReadManifest(args[0])
+		if err != nil {
+			return err
+		}
+		if len(m.Entries) == 0 {
+			return nil
+		}
+
+		if !force {
+			if !helpers.Prompt(fmt.Sprintf("Restore [%d] entries from manifest [%v]?", len(m.Entries), args[0])) {
+				os.Exit(0)
+			}
+		}
+
+		// refs are restored before PRs as a PR cannot be reopened without its head branch
+		entries := append([]*api.ManifestEntry(nil), m.Entries...)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Type != api.PRManifestEntry && entries[j].Type == api.PRManifestEntry
+		})
+
+		repositoryIds := make(map[string]string)
+		var restored, skipped []string
+		var errs error
+		for _, entry := range entries {
+			name := fmt.Sprintf("%v:%v:%v", entry.Repository, entry.Type, entry.Name)
+			switch entry.Type {
+			case api.BranchManifestEntry, api.TagManifestEntry:
+				repositoryId, found := repositoryIds[entry.Repository]
+				if !found {
+					composite := strings.SplitN(entry.Repository, "/", 2)
+					if len(composite) != 2 {
+						errs = errors.Join(errs, fmt.Errorf("invalid manifest repository: %v", entry.Repository))
+						continue
+					}
+					if repositoryId, err = ghApi.RepositoryId(cmd.Context(), composite[0], composite[1]); err != nil {
+						errs = errors.Join(errs, err)
+						continue
+					}
+					repositoryIds[entry.Repository] = repositoryId
+				}
+
+				prefix := api.BranchRefType
+				if entry.Type == api.TagManifestEntry {
+					prefix = api.TagRefType
+				}
+				err = ghApi.CreateRef(cmd.Context(), repositoryId, prefix+entry.Name, entry.Sha)
+			case api.PRManifestEntry:
+				err = ghApi.ReopenPRs(cmd.Context(), entry.Id)
+			default:
+				err = fmt.Errorf("unsupported manifest entry type: %v", entry.Type)
+			}
+			switch {
+			case err == nil:
+				restored = append(restored, name)
+			case entry.Type != api.PRManifestEntry && alreadyExists(err):
+				// the ref has been recreated (or restored) in the meantime
+				skipped = append(skipped, name)
+			default:
+				errs = errors.Join(errs, err)
+			}
+		}
+
+		out = fmt.Sprintf("Restored [entries=%v]\n", restored)
+		if len(skipped) != 0 {
+			out = fmt.Sprintf("%vSkipped (already exist) [entries=%v]\n", out, skipped)
+		}
+		if errs != nil {
+			return errors.Join(emit(), errs)
+		}
+		return nil
+	},
+}
+
+// alreadyExists reports whether the ref creation failed because a ref with the same name already exists.
+func alreadyExists(err error) bool {
+	return strings.Contains(err.Error(), "already exists")
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type retentionLine = string
+
+const (
+	majorRetentionLine retentionLine = "major"
+	minorRetentionLine               = "minor"
+)
+
+type tagSort = string
+
+const (
+	nameTagSort   tagSort = "name"
+	dateTagSort           = "date"
+	semverTagSort         = "semver"
+)
+
+var (
+	keepLatest          int
+	keepLine            string
+	keepReleased        bool
+	prereleaseThreshold time.Duration
+	tagSortBy           string
+)
+
+// tagRetention holds the semver-aware retention rules of tags.
+type tagRetention struct {
+	keepLatest          int
+	line                retentionLine
+	keepReleased        bool
+	prereleaseThreshold time.Duration
+	sort                tagSort
+}
+
+func newTagRetention(keep int, line retentionLine, released bool, prerelease time.Duration, sortBy tagSort) (*tagRetention, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("the number of tags to keep must not be negative")
+	}
+	if line != majorRetentionLine && line != minorRetentionLine {
+		return nil, fmt.Errorf("the retention line [%v] is not supported. Supported values are: major, minor", line)
+	}
+	switch sortBy {
+	case nameTagSort, dateTagSort, semverTagSort:
+	default:
+		return nil, fmt.Errorf("the tag sort [%v] is not supported. Supported values are: name, date, semver", sortBy)
+	}
+	return &tagRetention{
+		keepLatest:          keep,
+		line:                line,
+		keepReleased:        released,
+		prereleaseThreshold: prerelease,
+		sort:                sortBy,
+	}, nil
+}
+
+// retained returns the ids of the latest N tags of every semver major or minor line. Pre-release and non-semver tags
+// are not counted.
+func (r *tagRetention) retained(tags []*api.GitHubRef) map[string]bool {
+	out := make(map[string]bool)
+	if r.keepLatest == 0 {
+		return out
+	}
+
+	lines := make(map[string][]*api.GitHubRef)
+	versions := make(map[string]*api.Semver)
+	for _, tag := range tags {
+		version, err := api.ParseSemver(tag.Name)
+		if err != nil || version.IsPrerelease() {
+			continue
+		}
+		versions[tag.Id] = version
+		line := fmt.Sprintf("%d", version.Major)
+		if r.line == minorRetentionLine {
+			line = fmt.Sprintf("%d.%d", version.Major, version.Minor)
+		}
+		lines[line] = append(lines[line], tag)
+	}
+
+	for _, lineTags := range lines {
+		sort.SliceStable(lineTags, func(i, j int) bool {
+			return versions[lineTags[i].Id].Compare(versions[lineTags[j].Id]) > 0
+		})
+		for i := 0; i < len(lineTags) && i < r.keepLatest; i++ {
+			out[lineTags[i].Id] = true
+		}
+	}
+	return out
+}
+
+// isStale evaluates the staleness of the tag, using the pre-release threshold (if any) for pre-release tags.
+func (r *tagRetention) isStale(tag *api.GitHubRef, opts *staleOptions) bool {
+	if r.prereleaseThreshold != 0 {
+		if version, err := api.ParseSemver(tag.Name); err == nil && version.IsPrerelease() {
+			date := refDate(tag)
+			return date != nil && date.Before(time.Now().Add(-r.prereleaseThreshold))
+		}
+	}
+	return opts.isStale(refDate(tag))
+}
+
+// sortTags orders the tags by name (as listed), date or semver precedence. Non-semver tags are sorted last.
+func (r *tagRetention) sortTags(tags []*api.GitHubRef) {
+	switch r.sort {
+	case dateTagSort:
+		sort.SliceStable(tags, func(i, j int) bool {
+			a, b := refDate(tags[i]), refDate(tags[j])
+			return a != nil && (b == nil || a.Before(*b))
+		})
+	case semverTagSort:
+		sort.SliceStable(tags, func(i, j int) bool {
+			a, aErr := api.ParseSemver(tags[i].Name)
+			b, bErr := api.ParseSemver(tags[j].Name)
+			switch {
+			case aErr != nil && bErr != nil:
+				return tags[i].Name < tags[j].Name
+			case aErr != nil || bErr != nil:
+				return aErr == nil
+			}
+			return a.Compare(b) < 0
+		})
+	}
+}
+
+var (
+	keepLast     int
+	branchQuotas []string
+)
+
+// branchQuota keeps the most recent N branches matching its glob pattern, where '*' matches any characters.
+type branchQuota struct {
+	pattern string
+	glob    *regexp.Regexp
+	keep    int
+}
+
+// parseQuotas parses quotas in the '<glob>=<N>' format. A keep-last value greater than zero is a quota on every branch.
+func parseQuotas(last int, values []string) ([]*branchQuota, error) {
+	if last < 0 {
+		return nil, fmt.Errorf("the number of branches to keep must not be negative")
+	}
+	var out []*branchQuota
+	if last > 0 {
+		out = append(out, newBranchQuota("*", last))
+	}
+	for _, value := range values {
+		i := strings.LastIndex(value, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("the quota [%v] must use the '<glob>=<N>' format", value)
+		}
+		keep, err := strconv.Atoi(value[i+1:])
+		if err != nil || keep < 0 {
+			return nil, fmt.Errorf("the quota [%v] must keep a non-negative number of branches", value)
+		}
+		out = append(out, newBranchQuota(value[:i], keep))
+	}
+	return out, nil
+}
+
+func newBranchQuota(pattern string, keep int) *branchQuota {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return &branchQuota{pattern: pattern, glob: regexp.MustCompile("^" + expr + "$"), keep: keep}
+}
+
+// retainedBranches returns the ids of the branches kept by any of the quotas, i.e. the most recent N branches
+// matching each quota pattern.
+func retainedBranches(branches []*api.GitHubRef, quotas []*branchQuota) map[string]bool {
+	out := make(map[string]bool)
+	for _, quota := range quotas {
+		var matching []*api.GitHubRef
+		for _, branch := range branches {
+			if quota.glob.MatchString(branch.Name) {
+				matching = append(matching, branch)
+			}
+		}
+		sort.SliceStable(matching, func(i, j int) bool {
+			a, b := matching[i].LastCommitDate, matching[j].LastCommitDate
+			return a != nil && (b == nil || a.After(*b))
+		})
+		for i := 0; i < len(matching) && i < quota.keep; i++ {
+			out[matching[i].Id] = true
+		}
+	}
+	return out
+}
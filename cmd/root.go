@@ -5,6 +5,8 @@ import (
 	"github.com/pcanilho/gh-tidy/api"
 	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/spf13/cobra"
+	"log"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -15,30 +17,44 @@ var (
 	ghApi      *api.GitHub
 	serializer helpers.Serializer
 	out        any
+	manifest   *api.Manifest
+	// viewTargets maps the keys of the stale views to the repository they were collected from
+	viewTargets map[string]*repository
 )
 
 // commands
 var (
-	staleThreshold time.Duration
-	excludePattern string
-	excludeRegex   *regexp.Regexp
-	refs           []string
-	remove         bool
+	staleThreshold   time.Duration
+	excludePattern   string
+	excludeRegex     *regexp.Regexp
+	refs             []string
+	remove           bool
+	includeProtected bool
 )
 
 var (
 	owner         string
 	format        string
+	columns       []string
+	templateText  string
+	templatePath  string
 	force         bool
 	timed         bool
 	workerCount   int
 	enterpriseUrl string
+	manifestPath  string
+
+	appId             int64
+	appInstallationId int64
+	appPrivateKeyPath string
 )
 
 var (
 	startTime = time.Now()
 )
 
+const _defaultStaleThreshold = time.Hour * 24 * 7 * 4
+
 var rootCmd = &cobra.Command{
 	Use: "tidy",
 	Example: `$ direnv allow || read -s GITHUB_TOKEN; export GITHUB_TOKEN
@@ -48,7 +64,9 @@ $ gh tidy stale branches <owner/repo> -t 72h
 $ gh tidy stale branches <owner/repo> -t 72h
 $ gh tidy stale prs      <owner/repo> -t 72h -s OPEN -s MERGED
 $ gh tidy stale tags     <owner/repo> -t 72h
-$ gh tidy delete         <owner/repo> -t 72h --ref <branch_name> --ref <tag_name>`,
+$ gh tidy delete         <owner/repo> -t 72h --ref <branch_name> --ref <tag_name>
+$ gh tidy apply          -c tidy.yaml
+$ gh tidy plan           -c tidy.yaml --out plan.json && gh tidy apply plan.json`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Format
 		switch strings.TrimSpace(strings.ToLower(format)) {
@@ -56,9 +74,26 @@ $ gh tidy delete         <owner/repo> -t 72h --ref <branch_name> --ref <tag_name
 			serializer = new(helpers.JsonSerializer)
 		case helpers.YAML:
 			serializer = new(helpers.YamlSerializer)
+		case helpers.TABLE:
+			serializer = helpers.NewTableSerializer(columns...)
+		case helpers.CSV:
+			serializer = &helpers.CsvSerializer{Columns: columns}
+		case helpers.NDJSON:
+			serializer = &helpers.NdjsonSerializer{Columns: columns}
+		case helpers.MARKDOWN:
+			serializer = &helpers.MarkdownSerializer{Columns: columns}
+		case helpers.HTML:
+			serializer = &helpers.HtmlSerializer{Title: cmd.CommandPath(), Columns: columns}
+		case helpers.SARIF:
+			serializer = &helpers.SarifSerializer{Kind: findingKind(cmd), Threshold: staleThreshold}
+		case helpers.JUNIT:
+			serializer = &helpers.JUnitSerializer{Kind: findingKind(cmd), Threshold: staleThreshold}
 		default:
 			return fmt.Errorf("the provided format [%v] is not supported", format)
 		}
+		if serializer, err = templateSerializer(); err != nil {
+			return err
+		}
 
 		// Patterns
 		if len(excludePattern) > 0 {
@@ -69,56 +104,154 @@ $ gh tidy delete         <owner/repo> -t 72h --ref <branch_name> --ref <tag_name
 		}
 
 		// Internal :: Session
-		ghApi, err = api.NewSession(
+		opts := []api.Option{
 			api.WithEnterpriseEndpoint(enterpriseUrl),
 			api.WithContext(cmd.Context()),
-			api.WithWorkerCount(workerCount))
+			api.WithWorkerCount(workerCount),
+		}
+		if appId != 0 || appInstallationId != 0 || len(appPrivateKeyPath) != 0 {
+			opts = append(opts, api.WithGitHubAppKeyFile(appId, appInstallationId, appPrivateKeyPath))
+		}
+		auditor, err := newAuditor()
 		if err != nil {
 			return err
 		}
-		return nil
-	},
-	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
-		if out == nil || len(strings.TrimSpace(fmt.Sprintf("%v", out))) == 0 {
-			return nil
+		if auditor != nil {
+			opts = append(opts, api.WithAuditor(auditor))
 		}
-		content, err := serializer.Serialize(out)
+		ghApi, err = api.NewSession(opts...)
 		if err != nil {
-			return fmt.Errorf("[INTERNAL] unable to serialise output. Error: %v", err)
+			return err
 		}
-		fmt.Println(string(content))
 
-		if timed {
-			fmt.Printf("\nruntime: %v\n", time.Since(startTime))
-		}
-		return nil
+		// Internal :: Notifications
+		notifier, err = newNotifier()
+		return err
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		return emit()
 	},
 }
 
+// emit serializes & prints the output of the command, pointing at the backup manifest when one has been written.
+func emit() error {
+	if out == nil || len(strings.TrimSpace(fmt.Sprintf("%v", out))) == 0 {
+		return nil
+	}
+	content, err := serializer.Serialize(out)
+	if err != nil {
+		return fmt.Errorf("[INTERNAL] unable to serialise output. Error: %v", err)
+	}
+	fmt.Println(string(content))
+
+	if manifest != nil {
+		log.Printf("backup manifest written to: %v. Use 'gh tidy restore %v' to undo the changes...", manifest.Path(), manifest.Path())
+	}
+
+	if timed {
+		fmt.Printf("\nruntime: %v\n", time.Since(startTime))
+	}
+	return nil
+}
+
 var staleCmd = &cobra.Command{
 	Use:     "stale",
 	Aliases: []string{"inactive"},
 }
 
+// findingKind returns the kind of the entries reported by the command, e.g. 'branch' for 'stale branches'. The kind is
+// left empty for commands reporting mixed entries.
+func findingKind(cmd *cobra.Command) string {
+	switch cmd.Name() {
+	case staleBranchesCmd.Name(), staleBotBranchesCmd.Name():
+		return api.BranchManifestEntry
+	case staleTagsCmd.Name():
+		return api.TagManifestEntry
+	case stalePrsCmd.Name():
+		return api.PRManifestEntry
+	}
+	return ""
+}
+
+// templateSerializer returns the serializer rendering the [template] or [template-file] flag, if any, in place of the
+// selected format.
+func templateSerializer() (helpers.Serializer, error) {
+	if len(templateText) != 0 && len(templatePath) != 0 {
+		return nil, fmt.Errorf("the [template] & [template-file] flags are mutually exclusive")
+	}
+	text := templateText
+	if len(templatePath) != 0 {
+		content, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read output template: %v. error: %v", templatePath, err)
+		}
+		text = string(content)
+	}
+	if len(text) == 0 {
+		return serializer, nil
+	}
+	return helpers.NewTemplateSerializer(text)
+}
+
+// backupManifest returns the manifest recording every destructive operation of the current run.
+func backupManifest() *api.Manifest {
+	if manifest == nil {
+		path := manifestPath
+		if len(path) == 0 {
+			path = fmt.Sprintf("gh-tidy-manifest-%d.json", startTime.Unix())
+		}
+		manifest = api.NewManifest(path)
+	}
+	return manifest
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&owner, "owner", "o", "", "The GitHub owner value. (Automatically set if the repository is given in the 'owner/repository' format")
-	rootCmd.PersistentFlags().StringVar(&format, "format", "json", "The desired output format. Supported values are: yaml, json")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "json", "The desired output format. Supported values are: yaml, json, table, csv, ndjson, markdown, html, sarif, junit")
+	rootCmd.PersistentFlags().StringSliceVar(&columns, "columns", nil, "If provided, only the columns (fields) will be rendered by the table, csv, ndjson, markdown & html formats, e.g. repository,name,last_commit_date,author")
+	rootCmd.PersistentFlags().StringVar(&templateText, "template", "", "If provided, the output is rendered through the go template instead of the format. Available functions: age, humanize, join, upper")
+	rootCmd.PersistentFlags().StringVar(&templatePath, "template-file", "", "If provided, the output is rendered through the go template file instead of the format")
 	rootCmd.PersistentFlags().BoolVar(&remove, "rm", false, "If specified, this flag enable the removal mode of the correlated sub-command")
+	rootCmd.PersistentFlags().BoolVar(&includeProtected, "include-protected", false, "If specified, the default branch and refs covered by branch protection rules or rulesets may also be removed")
 	rootCmd.PersistentFlags().BoolVarP(&force, "force", "f", false, "If specified, all interactive operations will be disabled")
 	rootCmd.PersistentFlags().BoolVar(&timed, "timed", false, "If specified, the total execution time will be printed")
 	rootCmd.PersistentFlags().IntVar(&workerCount, "worker-count", 20, "The amount of concurrent workers carrying out internal tasks like ref. deletion & PR closing")
+	rootCmd.PersistentFlags().StringVar(&manifestPath, "manifest", "", "The path of the backup manifest written by destructive operations. [gh-tidy-manifest-<timestamp>.json]")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "If provided, every mutation (ref deletion, PR closing, comment...) is appended to the audit log (JSON lines) along with who carried it out & its result")
+	rootCmd.PersistentFlags().BoolVar(&auditSyslog, "audit-syslog", false, "If specified, every mutation is also sent to the local syslog daemon")
+	rootCmd.PersistentFlags().StringVar(&notifyWebhook, "notify-webhook", "", "If provided, the refs & PRs about to be removed are posted (JSON) to the webhook beforehand")
+	rootCmd.PersistentFlags().StringVar(&notifySlack, "notify-slack", "", "If provided, the refs & PRs about to be removed are posted to the Slack incoming webhook beforehand")
+	rootCmd.PersistentFlags().StringVar(&notifySmtp, "notify-smtp", "", "If provided, the refs & PRs about to be removed are emailed through the SMTP server ('host:port') beforehand. Credentials are read from SMTP_USERNAME & SMTP_PASSWORD")
+	rootCmd.PersistentFlags().StringVar(&notifySmtpFrom, "notify-smtp-from", "", "The sender address of the notification emails")
+	rootCmd.PersistentFlags().StringArrayVar(&notifySmtpTo, "notify-smtp-to", nil, "The recipient addresses of the notification emails")
+	rootCmd.PersistentFlags().StringVar(&notifyTemplatePath, "notify-template", "", "The path of the notification message template (go template). Available fields: .Action, .Items, .Groups")
 	rootCmd.PersistentFlags().StringVar(&enterpriseUrl, "enterprise", "", "If provided, the GitHub Enterprise API endpoint will be used instead")
+	rootCmd.PersistentFlags().Int64Var(&appId, "app-id", 0, "If provided, the session will be authenticated as the GitHub App with this id instead of using GITHUB_TOKEN")
+	rootCmd.PersistentFlags().Int64Var(&appInstallationId, "app-installation-id", 0, "The GitHub App installation id used to mint installation tokens")
+	rootCmd.PersistentFlags().StringVar(&appPrivateKeyPath, "app-private-key", "", "The path to the GitHub App private key (PEM)")
 
-	staleCmd.PersistentFlags().DurationVarP(&staleThreshold, "threshold", "t", time.Hour*24*7*4, "The stale threshold value. [1 month]")
+	staleCmd.PersistentFlags().DurationVarP(&staleThreshold, "threshold", "t", _defaultStaleThreshold, "The stale threshold value. [1 month]")
+	staleCmd.PersistentFlags().StringVar(&scanOrg, "org", "", "If provided, all repositories of the organisation will be scanned")
+	staleCmd.PersistentFlags().StringVar(&scanUser, "user", "", "If provided, all repositories owned by the user will be scanned")
+	staleCmd.PersistentFlags().BoolVar(&scanFilter.Archived, "include-archived", false, "If specified, archived repositories will also be scanned when using the [org] or [user] flags")
+	staleCmd.PersistentFlags().BoolVar(&scanFilter.Forks, "include-forks", false, "If specified, forked repositories will also be scanned when using the [org] or [user] flags")
+	staleCmd.PersistentFlags().StringArrayVar(&scanFilter.Visibility, "visibility", nil, "If provided, only repositories with the visibility will be scanned. Supported values are: public, private, internal")
+	staleCmd.PersistentFlags().StringArrayVar(&scanFilter.Topics, "topic", nil, "If provided, only repositories with at least one of the topics will be scanned")
+	staleCmd.PersistentFlags().StringVar(&scanFilter.Pattern, "repo-pattern", "", "If provided, only repositories whose name matches the pattern (regexp) will be scanned")
+	staleCmd.PersistentFlags().StringVar(&stalePlanPath, "plan-out", "", "If provided, the refs & PRs the [rm] flag would remove are written to the plan file and nothing is removed. See 'gh tidy apply <plan>'")
 
 	staleCmd.AddCommand(staleBranchesCmd)
+	staleCmd.AddCommand(staleBotBranchesCmd)
 	staleCmd.AddCommand(stalePrsCmd)
 	staleCmd.AddCommand(staleTagsCmd)
 
 	rootCmd.AddCommand(staleCmd)
 	rootCmd.AddCommand(deleteRefCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(planCmd)
 }
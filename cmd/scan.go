@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// repositoryFilter selects the repositories of an organisation or user that are scanned.
+type repositoryFilter struct {
+	Archived   bool     `json:"archived,omitempty" yaml:"archived,omitempty"`
+	Forks      bool     `json:"forks,omitempty" yaml:"forks,omitempty"`
+	Visibility []string `json:"visibility,omitempty" yaml:"visibility,omitempty"`
+	Topics     []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+	Pattern    string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+}
+
+var (
+	scanOrg    string
+	scanUser   string
+	scanFilter repositoryFilter
+)
+
+func (f *repositoryFilter) validate() error {
+	for _, visibility := range f.Visibility {
+		switch strings.ToUpper(visibility) {
+		case "PUBLIC", "PRIVATE", "INTERNAL":
+		default:
+			return fmt.Errorf("the visibility [%v] is not supported. Supported values are: public, private, internal", visibility)
+		}
+	}
+	if _, err := regexp.Compile(f.Pattern); err != nil {
+		return fmt.Errorf("invalid repository pattern [%v]. error: %v", f.Pattern, err)
+	}
+	return nil
+}
+
+func (f *repositoryFilter) selects(repo *api.GitHubRepository, pattern *regexp.Regexp) bool {
+	if repo.Archived && !f.Archived {
+		return false
+	}
+	if repo.Fork && !f.Forks {
+		return false
+	}
+	if len(f.Visibility) != 0 && !containsFold(f.Visibility, repo.Visibility) {
+		return false
+	}
+	if len(f.Topics) != 0 {
+		var found bool
+		for _, topic := range repo.Topics {
+			if containsFold(f.Topics, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return pattern == nil || pattern.MatchString(repo.Name)
+}
+
+// scanRepositories enumerates the repositories of the provided organisation or user login matching the filter.
+func scanRepositories(ctx context.Context, login string, filter *repositoryFilter) ([]*repository, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	var pattern *regexp.Regexp
+	if len(filter.Pattern) != 0 {
+		pattern = regexp.MustCompile(filter.Pattern)
+	}
+
+	repos, err := ghApi.ListRepositories(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	var out []*repository
+	for _, repo := range repos {
+		if filter.selects(repo, pattern) {
+			out = append(out, &repository{owner: repo.Owner, name: repo.Name})
+		}
+	}
+	return out, nil
+}
+
+// resolveTargets resolves the repositories targeted by a stale command out of its arguments and the
+// [org] or [user] scanning flags.
+func resolveTargets(ctx context.Context, args []string) ([]*repository, error) {
+	if len(scanOrg) != 0 && len(scanUser) != 0 {
+		return nil, fmt.Errorf("only one of the [org] or [user] flags can be provided")
+	}
+
+	var targets []*repository
+	for _, arg := range args {
+		o, repo, err := splitRepository(arg)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &repository{owner: o, name: repo})
+	}
+
+	if login := scanOrg + scanUser; len(login) != 0 {
+		scanned, err := scanRepositories(ctx, login, &scanFilter)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, scanned...)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one <owner>/<repository> or the [org] or [user] flag needs to be provided")
+	}
+	return targets, nil
+}
+
+// forEachRepository runs fn against every target concurrently, bounded by the session worker count.
+func forEachRepository(targets []*repository, fn func(*repository) error) error {
+	workers := workerCount
+	if workers < 1 {
+		workers = 1
+	}
+	ec := make(chan error, len(targets))
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	wg.Add(len(targets))
+	go func() {
+		wg.Wait()
+		close(ec)
+		close(sem)
+	}()
+
+	for _, target := range targets {
+		sem <- struct{}{}
+		go func(t *repository) {
+			if fnErr := fn(t); fnErr != nil {
+				ec <- fmt.Errorf("[%v] %v", t, fnErr)
+			}
+			wg.Done()
+			<-sem
+		}(target)
+	}
+
+	var err error
+	for e := range ec {
+		err = errors.Join(err, e)
+	}
+	return err
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexTargets(targets []*repository) map[string]*repository {
+	index := make(map[string]*repository, len(targets))
+	for _, target := range targets {
+		index[target.String()] = target
+	}
+	return index
+}
@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// staleOptions holds the selection rules shared by the stale commands and the policy rules.
+type staleOptions struct {
+	threshold time.Duration
+	include   *regexp.Regexp
+	exclude   *regexp.Regexp
+	states    []string
+	merged    bool
+
+	labels         []string
+	excludeLabels  []string
+	authors        []string
+	excludeAuthors []string
+	draftOnly      bool
+	activity       api.ActivitySource
+	deleteBranch   bool
+
+	includeProtected bool
+	lifecycle        *lifecycleOptions
+	tags             *tagRetention
+	quotas           []*branchQuota
+	withOpenPR       openPRMode
+	prStates         []string
+	openPRComment    *openPRCommentOptions
+
+	base           string
+	ahead          int
+	behindMoreThan int
+}
+
+var (
+	authorFilter        []string
+	excludeAuthorFilter []string
+)
+
+// repository identifies a repository targeted by a stale pipeline.
+type repository struct {
+	owner string
+	name  string
+}
+
+func (r *repository) String() string {
+	return r.owner + "/" + r.name
+}
+
+// staleFlagOptions builds the selection rules out of the command-line flags.
+func staleFlagOptions() (*staleOptions, error) {
+	opts := &staleOptions{
+		threshold: staleThreshold,
+		exclude:   excludeRegex,
+		states:    prState,
+		merged:    mergedOnly,
+
+		base:           baseBranch,
+		ahead:          aheadBy,
+		behindMoreThan: behindMoreThan,
+
+		labels:         prLabels,
+		excludeLabels:  prExcludeLabels,
+		authors:        authorFilter,
+		excludeAuthors: excludeAuthorFilter,
+		draftOnly:      prDraftOnly,
+		activity:       prActivitySource,
+		deleteBranch:   prDeleteBranch,
+
+		includeProtected: includeProtected,
+	}
+	if err := validateActivitySource(opts.activity); err != nil {
+		return nil, err
+	}
+	tags, err := newTagRetention(keepLatest, keepLine, keepReleased, prereleaseThreshold, tagSortBy)
+	if err != nil {
+		return nil, err
+	}
+	opts.tags = tags
+	if opts.quotas, err = parseQuotas(keepLast, branchQuotas); err != nil {
+		return nil, err
+	}
+	opts.withOpenPR, opts.prStates = withOpenPR, branchPRStates
+	if err = validateBranchPRFilters(opts.withOpenPR, opts.prStates); err != nil {
+		return nil, err
+	}
+	if opts.openPRComment, err = newOpenPRCommentOptions(openPRComment, openPRMessage, openPRGrace); err != nil {
+		return nil, err
+	}
+	if lifecycleEnabled {
+		// the comments & labels of the lifecycle itself bump the update & comment dates of the PRs
+		switch opts.activity {
+		case api.CommitActivitySource, api.ReviewActivitySource:
+		default:
+			return nil, fmt.Errorf("the [lifecycle] flag requires the activity source to be either commit or review, got [%v]", opts.activity)
+		}
+		lifecycle, err := newLifecycleOptions(staleLabel, staleMessage, gracePeriod)
+		if err != nil {
+			return nil, err
+		}
+		opts.lifecycle = lifecycle
+	}
+	return opts, nil
+}
+
+func (o *staleOptions) selects(name string) bool {
+	if o.include != nil && !o.include.MatchString(name) {
+		return false
+	}
+	return o.exclude == nil || !o.exclude.MatchString(name)
+}
+
+// selectsPR applies the PR metadata filters. PRs must carry at least one of the labels (if any), none of the
+// excluded labels and be authored by one of the authors (if any).
+func (o *staleOptions) selectsPR(pr *api.GitHubPR) bool {
+	if !o.selects(pr.Source) {
+		return false
+	}
+	if o.draftOnly && !pr.Draft {
+		return false
+	}
+	if len(o.authors) != 0 && !containsFold(o.authors, pr.Author) {
+		return false
+	}
+	if len(o.labels) != 0 {
+		var found bool
+		for _, label := range pr.Labels {
+			if containsFold(o.labels, label) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, label := range pr.Labels {
+		if containsFold(o.excludeLabels, label) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectsAuthor applies the author filters to the head commit author of the ref, matching its login, name or email.
+func (o *staleOptions) selectsAuthor(ref *api.GitHubRef) bool {
+	if len(o.authors) != 0 && !ref.Author.Matches(o.authors...) {
+		return false
+	}
+	return !ref.Author.Matches(o.excludeAuthors...)
+}
+
+// compares reports whether the branch filters require comparing branches against the base branch.
+func (o *staleOptions) compares() bool {
+	return o.merged || len(o.base) != 0 || o.ahead >= 0 || o.behindMoreThan >= 0
+}
+
+// selectsDivergence applies the ahead & behind filters to the compared branch.
+func (o *staleOptions) selectsDivergence(branch *api.GitHubRef) bool {
+	if o.ahead >= 0 && (branch.AheadBy == nil || *branch.AheadBy != o.ahead) {
+		return false
+	}
+	return o.behindMoreThan < 0 || (branch.BehindBy != nil && *branch.BehindBy > o.behindMoreThan)
+}
+
+// associatesPRs reports whether the branch filters require the PRs associated with each branch.
+func (o *staleOptions) associatesPRs() bool {
+	return (len(o.withOpenPR) != 0 && o.withOpenPR != includeOpenPRMode) || len(o.prStates) != 0 || o.openPRComment != nil
+}
+
+// selectsBranchPR applies the PR association filters: open PR handling and the state of the latest PR (if any).
+func (o *staleOptions) selectsBranchPR(branch *api.GitHubRef) bool {
+	switch o.withOpenPR {
+	case skipOpenPRMode:
+		if branch.HasOpenPR() {
+			return false
+		}
+	case onlyOpenPRMode:
+		if !branch.HasOpenPR() {
+			return false
+		}
+	}
+	if len(o.prStates) == 0 {
+		return true
+	}
+	return len(branch.PullRequests) != 0 && containsFold(o.prStates, branch.PullRequests[0].State)
+}
+
+func validateBranchPRFilters(mode openPRMode, states []string) error {
+	switch mode {
+	case "", skipOpenPRMode, includeOpenPRMode, onlyOpenPRMode:
+	default:
+		return fmt.Errorf("the open PR mode [%v] is not supported. Supported values are: skip, include, only", mode)
+	}
+	for _, state := range states {
+		switch strings.ToUpper(state) {
+		case "OPEN", "MERGED", "CLOSED":
+		default:
+			return fmt.Errorf("the PR state [%v] is not supported. Supported values are: OPEN, MERGED or CLOSED", state)
+		}
+	}
+	return nil
+}
+
+func (o *staleOptions) isStale(date *time.Time) bool {
+	return date != nil && date.Before(time.Now().Add(-o.threshold))
+}
+
+// isStalePR evaluates the staleness of the PR against its last activity of the configured source.
+func (o *staleOptions) isStalePR(pr *api.GitHubPR) bool {
+	activity := pr.LastActivity(o.activity)
+	return o.isStale(&activity)
+}
+
+func validateActivitySource(source api.ActivitySource) error {
+	switch source {
+	case api.CommitActivitySource, api.UpdatedActivitySource, api.CommentActivitySource, api.ReviewActivitySource, api.MaxActivitySource:
+		return nil
+	}
+	return fmt.Errorf("the activity source [%v] is not supported. Supported values are: commit, updated, comment, review, max", source)
+}
+
+// splitRepository resolves the owner of the provided repository using either the 'owner/repository' format or the
+// [owner] flag.
+func splitRepository(repository string) (string, string, error) {
+	if len(owner) == 0 && strings.Contains(repository, "/") {
+		composite := strings.Split(repository, "/")
+		owner, repository = composite[0], composite[1]
+	}
+
+	// Owner
+	if len(owner) == 0 {
+		return "", "", fmt.Errorf("the [owner] flag must be provided")
+	}
+	return owner, repository, nil
+}
+
+func staleBranches(ctx context.Context, owner, repo string, opts *staleOptions) ([]*api.GitHubRef, error) {
+	var listOpts []api.ListRefsOption
+	if opts.associatesPRs() {
+		listOpts = append(listOpts, api.WithAssociatedPRs())
+	}
+	brs, err := ghApi.ListRefs(ctx, owner, repo, api.BranchRefType, listOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.compares() {
+		base := opts.base
+		if len(base) == 0 {
+			if base, err = ghApi.DefaultBranch(ctx, owner, repo); err != nil {
+				return nil, err
+			}
+		}
+		var candidates []*api.GitHubRef
+		var found bool
+		for _, branch := range brs {
+			if branch.Name != base {
+				candidates = append(candidates, branch)
+				continue
+			}
+			found = true
+		}
+		// branches cannot be compared against a missing base, which would otherwise leave them all unselected
+		if !found {
+			return nil, fmt.Errorf("the base branch [%v] does not exist in repo [%v/%v]", base, owner, repo)
+		}
+		if err = ghApi.CompareRefs(ctx, base, candidates...); err != nil {
+			return nil, err
+		}
+		brs = candidates
+	}
+	retained := retainedBranches(brs, opts.quotas)
+
+	var filteredBranches []*api.GitHubRef
+	for _, branch := range brs {
+		if !opts.selects(branch.Name) || retained[branch.Id] || !opts.selectsAuthor(branch) ||
+			!opts.selectsBranchPR(branch) || !opts.selectsDivergence(branch) {
+			continue
+		}
+
+		if opts.merged {
+			if branch.Merged != nil && *branch.Merged {
+				filteredBranches = append(filteredBranches, branch)
+			}
+			continue
+		}
+
+		if opts.isStale(branch.LastCommitDate) {
+			filteredBranches = append(filteredBranches, branch)
+		}
+	}
+	return filteredBranches, nil
+}
+
+func staleTags(ctx context.Context, owner, repo string, opts *staleOptions) ([]*api.GitHubRef, error) {
+	tags, err := ghApi.ListRefs(ctx, owner, repo, api.TagRefType)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.tags.keepReleased {
+		releases, err := ghApi.ListReleaseTags(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			tag.Released = containsFold(releases, tag.Name)
+		}
+	}
+	retained := opts.tags.retained(tags)
+
+	var filteredTags []*api.GitHubRef
+	for _, tag := range tags {
+		if !opts.selects(tag.Name) {
+			continue
+		}
+
+		if retained[tag.Id] || (opts.tags.keepReleased && tag.Released) {
+			continue
+		}
+
+		if opts.tags.isStale(tag, opts) {
+			filteredTags = append(filteredTags, tag)
+		}
+	}
+	opts.tags.sortTags(filteredTags)
+	return filteredTags, nil
+}
+
+func stalePRs(ctx context.Context, owner, repo string, opts *staleOptions) ([]*api.GitHubPR, error) {
+	prs, err := ghApi.ListPRs(ctx, opts.states, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredPRs []*api.GitHubPR
+	for _, pr := range prs {
+		if !opts.selectsPR(pr) {
+			continue
+		}
+
+		if opts.lifecycle != nil {
+			if pr.State != "OPEN" {
+				continue
+			}
+			if pr.Lifecycle = opts.stage(pr); len(pr.Lifecycle) != 0 {
+				filteredPRs = append(filteredPRs, pr)
+			}
+			continue
+		}
+
+		if opts.isStalePR(pr) {
+			filteredPRs = append(filteredPRs, pr)
+		}
+	}
+	return filteredPRs, nil
+}
+
+// headBranches resolves the head branches of the provided PRs that can be deleted alongside them. Branches of forks,
+// branches that moved past the PR head, branches backing other open PRs and protected branches (unless the
+// protection override has been requested) are skipped.
+func headBranches(ctx context.Context, owner, repo string, prs []*api.GitHubPR, opts *staleOptions) ([]*api.GitHubRef, error) {
+	var candidates []*api.GitHubPR
+	for _, pr := range prs {
+		if !pr.CrossRepository {
+			candidates = append(candidates, pr)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	brs, err := ghApi.ListRefs(ctx, owner, repo, api.BranchRefType, api.WithAssociatedPRs())
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*api.GitHubRef, len(brs))
+	for _, branch := range brs {
+		byName[branch.Name] = branch
+	}
+
+	var out []*api.GitHubRef
+	seen := make(map[string]bool)
+	for _, pr := range candidates {
+		branch, found := byName[pr.Source]
+		if !found || seen[branch.Id] || branch.Sha != pr.HeadSha {
+			continue
+		}
+		var backsOther bool
+		for _, associated := range branch.PullRequests {
+			if associated.Id != pr.Id && associated.State == "OPEN" {
+				backsOther = true
+			}
+		}
+		if backsOther {
+			continue
+		}
+		seen[branch.Id] = true
+		out = append(out, branch)
+	}
+	return deletableRefs(out, opts.includeProtected), nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/api"
+	"github.com/spf13/cobra"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type orphanReason = string
+
+const (
+	// mergedOrphanReason bot branches whose PR has been merged
+	mergedOrphanReason orphanReason = "merged"
+	// closedOrphanReason bot branches whose PR has been closed without being merged
+	closedOrphanReason = "closed"
+	// supersededOrphanReason bot branches whose PR has been closed in favour of an open PR for the same dependency
+	supersededOrphanReason = "superseded"
+	// noPROrphanReason stale bot branches without any PR
+	noPROrphanReason = "no-pr"
+)
+
+var (
+	botPrefixes []string
+	botAuthors  []string
+)
+
+// dependencyVersion matches the version suffix of bot branch names, e.g. 'dependabot/npm_and_yarn/lodash-4.17.21'
+// or 'renovate/lodash-4.x'.
+var dependencyVersion = regexp.MustCompile(`[-_]v?\d+(\.(\d+|x))*(-[0-9A-Za-z.]+)?$`)
+
+// botOptions identify the branches created by dependency bots.
+type botOptions struct {
+	prefixes []string
+	authors  []string
+}
+
+// owns reports whether the branch belongs to a dependency bot: its name starts with one of the prefixes, or any of its
+// associated PRs or its head commit is authored by one of the bots.
+func (b *botOptions) owns(branch *api.GitHubRef) bool {
+	for _, prefix := range b.prefixes {
+		if strings.HasPrefix(strings.ToLower(branch.Name), strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	for _, pr := range branch.PullRequests {
+		if containsFold(b.authors, pr.Author) {
+			return true
+		}
+	}
+	return branch.Author.Matches(b.authors...)
+}
+
+var staleBotBranchesCmd = &cobra.Command{
+	Use:     "bot-branches",
+	Aliases: []string{"bots"},
+	Example: `$ gh tidy stale bot-branches <owner/repo>
+$ gh tidy stale bot-branches --org <org> --bot-prefix 'deps/' --bot-author 'my-bot' --rm`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets, err := resolveTargets(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		opts, err := staleFlagOptions()
+		if err != nil {
+			return err
+		}
+		bots := &botOptions{prefixes: botPrefixes, authors: botAuthors}
+		view := make(map[string][]*api.GitHubRef)
+		var mu sync.Mutex
+		err = forEachRepository(targets, func(target *repository) error {
+			brs, err := staleBotBranches(cmd.Context(), target.owner, target.name, opts, bots)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = brs
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		viewTargets = indexTargets(targets)
+		out = view
+		return nil
+	},
+	PostRunE: func(cmd *cobra.Command, args []string) error {
+		if out == nil {
+			return fmt.Errorf("no results found")
+		}
+		view := out.(map[string][]*api.GitHubRef)
+		if len(stalePlanPath) != 0 {
+			return planStaleRefs(cmd, api.BranchRefType, view)
+		}
+		if remove {
+			opts, err := staleFlagOptions()
+			if err != nil {
+				return err
+			}
+			var pending removals
+			for repo, branches := range view {
+				target := viewTargets[repo]
+				pending.addRefs(target, api.BranchRefType, confirmRefs(target.name, api.BranchRefType, branches, opts))
+			}
+			return pending.carryOut(cmd.Context())
+		}
+		return nil
+	},
+}
+
+// staleBotBranches selects the orphaned branches of dependency bots: branches whose PR was merged, closed or
+// superseded, and stale branches that never had a PR. Branches with an open PR are never selected.
+func staleBotBranches(ctx context.Context, owner, repo string, opts *staleOptions, bots *botOptions) ([]*api.GitHubRef, error) {
+	brs, err := ghApi.ListRefs(ctx, owner, repo, api.BranchRefType, api.WithAssociatedPRs())
+	if err != nil {
+		return nil, err
+	}
+
+	var botBranches []*api.GitHubRef
+	open := make(map[string]bool)
+	for _, branch := range brs {
+		if !opts.selects(branch.Name) || !bots.owns(branch) {
+			continue
+		}
+		botBranches = append(botBranches, branch)
+		if branch.HasOpenPR() {
+			open[dependencyVersion.ReplaceAllString(branch.Name, "")] = true
+		}
+	}
+
+	var filteredBranches []*api.GitHubRef
+	for _, branch := range botBranches {
+		if branch.HasOpenPR() {
+			continue
+		}
+		switch {
+		case len(branch.PullRequests) == 0:
+			if !opts.isStale(branch.LastCommitDate) {
+				continue
+			}
+			branch.Orphaned = noPROrphanReason
+		case branch.PullRequests[0].State == "MERGED":
+			branch.Orphaned = mergedOrphanReason
+		case open[dependencyVersion.ReplaceAllString(branch.Name, "")]:
+			branch.Orphaned = supersededOrphanReason
+		default:
+			branch.Orphaned = closedOrphanReason
+		}
+		filteredBranches = append(filteredBranches, branch)
+	}
+	return filteredBranches, nil
+}
+
+func init() {
+	staleBotBranchesCmd.PersistentFlags().StringArrayVar(&botPrefixes, "bot-prefix", []string{"dependabot/", "renovate/"}, "The branch name prefixes identifying the branches of dependency bots")
+	staleBotBranchesCmd.PersistentFlags().StringArrayVar(&botAuthors, "bot-author", []string{"dependabot", "dependabot[bot]", "renovate", "renovate[bot]"}, "The PR or head commit author logins identifying the branches of dependency bots")
+	staleBotBranchesCmd.PersistentFlags().StringVar(&excludePattern, "exclude", "", "If provided, it will be used to exclude branches that match the pattern (regexp)")
+}
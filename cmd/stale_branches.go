@@ -3,78 +3,146 @@ package cmd
 import (
 	"fmt"
 	"github.com/pcanilho/gh-tidy/api"
-	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/spf13/cobra"
-	"strings"
+	"sync"
 	"time"
 )
 
+type openPRMode = string
+
+const (
+	skipOpenPRMode    openPRMode = "skip"
+	includeOpenPRMode            = "include"
+	onlyOpenPRMode               = "only"
+)
+
+var (
+	mergedOnly     bool
+	withOpenPR     string
+	branchPRStates []string
+
+	baseBranch     string
+	aheadBy        int
+	behindMoreThan int
+
+	groupByOwner bool
+	// branchesView holds the stale branches per repository when the output is grouped differently
+	branchesView map[string][]*api.GitHubRef
+)
+
+const _unknownOwner = "unknown"
+
 var staleBranchesCmd = &cobra.Command{
 	Use:     "branches",
 	Aliases: []string{"b", "br"},
-	Example: `$ gh tidy stale branches <owner/repo> -t 72h`,
+	Example: `$ gh tidy stale branches <owner/repo> -t 72h
+$ gh tidy stale branches --org <org> --topic <topic> -t 72h
+$ gh tidy stale branches <owner/repo> -t 72h --quota 'release/*=10' --quota 'renovate/*=5'
+$ gh tidy stale branches <owner/repo> -t 72h --with-open-pr skip --pr-state MERGED --pr-state CLOSED
+$ gh tidy stale branches <owner/repo> -t 72h --base develop --ahead 0
+$ gh tidy stale branches --org <org> -t 72h --exclude-author 'octocat' --group-by-owner
+$ gh tidy stale branches <owner/repo> -t 72h --open-pr-comment instead --open-pr-grace 168h --rm`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return fmt.Errorf("at least one <owner>/<repository> needs to be provided")
+		targets, err := resolveTargets(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		opts, err := staleFlagOptions()
+		if err != nil {
+			return err
 		}
 		view := make(map[string][]*api.GitHubRef)
-		for _, repo := range args {
-			if len(owner) == 0 && strings.Contains(repo, "/") {
-				composite := strings.Split(repo, "/")
-				owner, repo = composite[0], composite[1]
-			}
-
-			// Owner
-			if len(owner) == 0 {
-				return fmt.Errorf("the [owner] flag must be provided")
-			}
-			brs, err := ghApi.ListRefs(cmd.Context(), owner, repo, api.BranchRefType)
+		var mu sync.Mutex
+		err = forEachRepository(targets, func(target *repository) error {
+			brs, err := staleBranches(cmd.Context(), target.owner, target.name, opts)
 			if err != nil {
 				return err
 			}
-			view[repo] = brs
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = brs
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-
-		for repo, branches := range view {
-			var filteredBranches []*api.GitHubRef
-			for _, branch := range branches {
-				if excludeRegex != nil && excludeRegex.MatchString(branch.Name) {
-					continue
-				}
-
-				if branch.LastCommitDate.Before(time.Now().Add(-staleThreshold)) {
-					filteredBranches = append(filteredBranches, branch)
-				}
-			}
-			view[repo] = filteredBranches
+		viewTargets = indexTargets(targets)
+		branchesView, out = view, view
+		if groupByOwner {
+			out = ownersView(view)
 		}
-		out = view
 		return nil
 	},
 	PostRunE: func(cmd *cobra.Command, args []string) error {
 		if out == nil {
 			return fmt.Errorf("no results found")
 		}
-		view := out.(map[string][]*api.GitHubRef)
+		view := branchesView
+		if len(stalePlanPath) != 0 {
+			return planStaleRefs(cmd, api.BranchRefType, view)
+		}
 		if remove {
+			opts, err := staleFlagOptions()
+			if err != nil {
+				return err
+			}
+			var pending removals
 			for repo, branches := range view {
-				if !force {
-					if !helpers.Prompt(fmt.Sprintf("Delete [%d] branches in repo [%v]?", len(branches), repo)) {
-						continue
-					}
-				}
-
-				for _, branch := range branches {
-					if err := ghApi.DeleteRefs(cmd.Context(), branch.Id); err != nil {
-						return fmt.Errorf("unable to delete branch: %v. error: %v", branch.Name, err)
-					}
+				target := viewTargets[repo]
+				deletable, comments, err := confirmBranches(cmd.Context(), target, branches, opts)
+				if err != nil {
+					return err
 				}
+				pending.addBranches(target, deletable, comments)
 			}
+			return pending.carryOut(cmd.Context())
 		}
 		return nil
 	},
 }
 
+// ownersView groups the stale branches of each repository by the owner of their head commit, i.e. its author login,
+// email or name.
+func ownersView(view map[string][]*api.GitHubRef) map[string]map[string][]*api.GitHubRef {
+	out := make(map[string]map[string][]*api.GitHubRef)
+	for repo, branches := range view {
+		for _, branch := range branches {
+			owner := refOwner(branch)
+			if out[owner] == nil {
+				out[owner] = make(map[string][]*api.GitHubRef)
+			}
+			out[owner][repo] = append(out[owner][repo], branch)
+		}
+	}
+	return out
+}
+
+func refOwner(ref *api.GitHubRef) string {
+	if ref.Author == nil {
+		return _unknownOwner
+	}
+	for _, value := range []string{ref.Author.Login, ref.Author.Email, ref.Author.Name} {
+		if len(value) != 0 {
+			return value
+		}
+	}
+	return _unknownOwner
+}
+
 func init() {
+	staleBranchesCmd.PersistentFlags().BoolVar(&mergedOnly, "merged", false, "If specified, branches already merged into the base branch will be selected regardless of their age")
+	staleBranchesCmd.PersistentFlags().StringArrayVar(&authorFilter, "author", nil, "If provided, only branches whose head commit author (login, name or email) is one of the authors will be selected")
+	staleBranchesCmd.PersistentFlags().StringArrayVar(&excludeAuthorFilter, "exclude-author", nil, "If provided, branches whose head commit author (login, name or email) is one of the authors will be excluded")
+	staleBranchesCmd.PersistentFlags().BoolVar(&groupByOwner, "group-by-owner", false, "If specified, the stale branches are grouped by the owner (author) of their head commit")
+	staleBranchesCmd.PersistentFlags().StringVar(&baseBranch, "base", "", "The branch against which branches are compared by the [merged], [ahead] & [behind-more-than] flags. [default branch]")
+	staleBranchesCmd.PersistentFlags().IntVar(&aheadBy, "ahead", -1, "If provided, only branches with exactly N commits ahead of the base branch will be selected (e.g. 0 for branches without unique commits)")
+	staleBranchesCmd.PersistentFlags().IntVar(&behindMoreThan, "behind-more-than", -1, "If provided, only branches with more than N commits behind the base branch will be selected")
+	staleBranchesCmd.PersistentFlags().StringVar(&openPRComment, "open-pr-comment", noOpenPRComment, "How the open PRs of the branches being removed are commented on. Supported values are: none, before (deleting), instead (of deleting)")
+	staleBranchesCmd.PersistentFlags().StringVar(&openPRMessage, "open-pr-message", _defaultOpenPRMessage, "The comment (go template) posted on the open PRs of the branches being removed. Available fields: .Repository, .Branch, .PR, .DeletionDate")
+	staleBranchesCmd.PersistentFlags().DurationVar(&openPRGrace, "open-pr-grace", time.Hour*24*7, "The period after which branches commented on 'instead' of being deleted are deleted by a later run. [1 week]")
+	staleBranchesCmd.PersistentFlags().StringVar(&withOpenPR, "with-open-pr", includeOpenPRMode, "How branches backing an open PR are handled. Supported values are: skip, include, only")
+	staleBranchesCmd.PersistentFlags().StringArrayVar(&branchPRStates, "pr-state", nil, "If provided, only branches whose latest PR is in one of the states will be selected. Supported values are: OPEN, MERGED or CLOSED")
+	staleBranchesCmd.PersistentFlags().IntVar(&keepLast, "keep-last", 0, "If provided, the N most recent branches are kept regardless of their age")
+	staleBranchesCmd.PersistentFlags().StringArrayVar(&branchQuotas, "quota", nil, "If provided, the N most recent branches matching the glob are kept regardless of their age. Format: '<glob>=<N>' (e.g. 'release/*=10')")
 	staleBranchesCmd.PersistentFlags().StringVar(&excludePattern, "exclude", "", "If provided, it will be used to exclude branches that match the pattern (regexp)")
 }
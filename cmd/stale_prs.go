@@ -3,58 +3,54 @@ package cmd
 import (
 	"fmt"
 	"github.com/pcanilho/gh-tidy/api"
-	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/spf13/cobra"
-	"strings"
+	"sync"
 	"time"
 )
 
 var (
-	prState []string
+	prState         []string
+	prLabels        []string
+	prExcludeLabels []string
+	prDraftOnly     bool
+
+	prActivitySource string
+	prDeleteBranch   bool
 )
 
 var stalePrsCmd = &cobra.Command{
 	Use:     "prs",
 	Aliases: []string{"pr"},
-	Example: `$ gh tidy stale prs <owner/repo> -t 72h`,
+	Example: `$ gh tidy stale prs <owner/repo> -t 72h
+$ gh tidy stale prs <owner/repo> -t 72h --activity-source max
+$ gh tidy stale prs <owner/repo> -t 72h -s OPEN -s MERGED --delete-branch --rm
+$ gh tidy stale prs <owner/repo> -t 72h --lifecycle --grace-period 168h --rm`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return fmt.Errorf("at least one <owner>/<repository> needs to be provided")
+		targets, err := resolveTargets(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		opts, err := staleFlagOptions()
+		if err != nil {
+			return err
 		}
 		view := make(map[string][]*api.GitHubPR)
-		for _, repo := range args {
-			if len(owner) == 0 && strings.Contains(repo, "/") {
-				composite := strings.Split(repo, "/")
-				owner, repo = composite[0], composite[1]
-			}
-
-			// Owner
-			if len(owner) == 0 {
-				return fmt.Errorf("the [owner] flag must be provided")
-			}
-			prs, err := ghApi.ListPRs(cmd.Context(), prState, owner, repo)
+		var mu sync.Mutex
+		err = forEachRepository(targets, func(target *repository) error {
+			prs, err := stalePRs(cmd.Context(), target.owner, target.name, opts)
 			if err != nil {
 				return err
 			}
-			view[repo] = prs
-		}
-		for repo, prs := range view {
-			var filteredBranches []*api.GitHubPR
-			for _, pr := range prs {
-				if pr.LastCommitDate.Before(time.Now().Add(-staleThreshold)) {
-					filteredBranches = append(filteredBranches, pr)
-				}
-			}
-			view[repo] = filteredBranches
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = prs
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		viewTargets = indexTargets(targets)
 		out = view
-		//if len(args) == 1 {
-		//	repo := args[0]
-		//	if strings.Contains(repo, "/") {
-		//		repo = strings.Split(repo, "/")[1]
-		//	}
-		//	out = view[repo]
-		//}
 		return nil
 	},
 	PostRunE: func(cmd *cobra.Command, args []string) error {
@@ -62,28 +58,58 @@ var stalePrsCmd = &cobra.Command{
 			return fmt.Errorf("not results found")
 		}
 		view := out.(map[string][]*api.GitHubPR)
+		if len(stalePlanPath) != 0 {
+			return planStalePRs(cmd.Context(), cmd, view)
+		}
 		if remove {
+			opts, err := staleFlagOptions()
+			if err != nil {
+				return err
+			}
+			var pending removals
 			for repo, prs := range view {
-				if !force {
-					if !helpers.Prompt(fmt.Sprintf("Close [%d] PRs in repo [%v]?", len(prs), repo)) {
-						fmt.Println("cancelled...")
-						return nil
-					}
+				target := viewTargets[repo]
+				var proceed bool
+				closing, closed := prs, prs
+				if opts.lifecycle != nil {
+					closing, proceed, err = applyLifecycle(cmd.Context(), target.owner, target.name, prs, opts)
+					closed = closing
+				} else {
+					closing, proceed = confirmPRs(target.name, prs)
 				}
-
-				var ids []string
-				for _, pr := range prs {
-					ids = append(ids, pr.Id)
-				}
-				if err := ghApi.ClosePRs(cmd.Context(), ids...); err != nil {
+				if err != nil {
 					return err
 				}
+				if !proceed {
+					fmt.Println("cancelled...")
+					return nil
+				}
+				var heads []*api.GitHubRef
+				if opts.deleteBranch {
+					brs, err := headBranches(cmd.Context(), target.owner, target.name, closed, opts)
+					if err != nil {
+						return err
+					}
+					heads = confirmRefs(target.name, api.BranchRefType, brs, opts)
+				}
+				pending.addPRs(target, closing, heads)
 			}
+			return pending.carryOut(cmd.Context())
 		}
 		return nil
 	},
 }
 
 func init() {
+	stalePrsCmd.PersistentFlags().BoolVar(&lifecycleEnabled, "lifecycle", false, "If specified, stale PRs are first commented on & labelled and only closed after the grace period elapses without new activity")
+	stalePrsCmd.PersistentFlags().StringVar(&staleLabel, "stale-label", "stale", "The label added to stale PRs by the lifecycle")
+	stalePrsCmd.PersistentFlags().StringVar(&staleMessage, "stale-message", _defaultStaleMessage, "The comment (go template) posted on stale PRs by the lifecycle. Available fields: .PR, .Label, .GracePeriod, .CloseDate")
+	stalePrsCmd.PersistentFlags().DurationVar(&gracePeriod, "grace-period", time.Hour*24*7, "The period after which labelled PRs without new activity are closed by the lifecycle. [1 week]")
 	stalePrsCmd.PersistentFlags().StringArrayVarP(&prState, "state", "s", []string{"OPEN"}, "The PR state. Supported values are: OPEN, MERGED or CLOSED")
+	stalePrsCmd.PersistentFlags().StringArrayVar(&prLabels, "label", nil, "If provided, only PRs with at least one of the labels will be selected")
+	stalePrsCmd.PersistentFlags().StringArrayVar(&prExcludeLabels, "exclude-label", nil, "If provided, PRs with any of the labels will be excluded")
+	stalePrsCmd.PersistentFlags().StringArrayVar(&authorFilter, "author", nil, "If provided, only PRs opened by one of the authors (login) will be selected")
+	stalePrsCmd.PersistentFlags().StringVar(&prActivitySource, "activity-source", api.CommitActivitySource, "The activity used to evaluate the staleness of PRs. Supported values are: commit, updated, comment, review, max. The [lifecycle] flag only supports commit & review")
+	stalePrsCmd.PersistentFlags().BoolVar(&prDeleteBranch, "delete-branch", false, "If specified, the head branches of closed or merged PRs are deleted, except for forks, protected branches & branches backing other open PRs")
+	stalePrsCmd.PersistentFlags().BoolVar(&prDraftOnly, "draft-only", false, "If specified, only draft PRs will be selected")
 }
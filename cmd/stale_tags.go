@@ -3,55 +3,40 @@ package cmd
 import (
 	"fmt"
 	"github.com/pcanilho/gh-tidy/api"
-	"github.com/pcanilho/gh-tidy/api/helpers"
 	"github.com/spf13/cobra"
-	"strings"
-	"time"
+	"sync"
 )
 
 var staleTagsCmd = &cobra.Command{
 	Use:     "tags",
 	Aliases: []string{"t"},
-	Example: `$ gh tidy stale tags <owner/repo> -t 72h`,
+	Example: `$ gh tidy stale tags <owner/repo> -t 72h
+$ gh tidy stale tags <owner/repo> -t 72h --keep-latest 3 --keep-per minor --keep-released --prerelease-threshold 336h --sort semver`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return fmt.Errorf("at least one <owner>/<repository> needs to be provided")
+		targets, err := resolveTargets(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		opts, err := staleFlagOptions()
+		if err != nil {
+			return err
 		}
 		view := make(map[string][]*api.GitHubRef)
-		for _, repo := range args {
-			if len(owner) == 0 && strings.Contains(repo, "/") {
-				composite := strings.Split(repo, "/")
-				owner, repo = composite[0], composite[1]
-			}
-
-			// Owner
-			if len(owner) == 0 {
-				return fmt.Errorf("the [owner] flag must be provided")
-			}
-			tags, err := ghApi.ListRefs(cmd.Context(), owner, repo, api.TagRefType)
+		var mu sync.Mutex
+		err = forEachRepository(targets, func(target *repository) error {
+			tags, err := staleTags(cmd.Context(), target.owner, target.name, opts)
 			if err != nil {
 				return err
 			}
-			view[repo] = tags
-		}
-
-		for repo, tags := range view {
-			var filteredTags []*api.GitHubRef
-			for _, tag := range tags {
-				if excludeRegex != nil && excludeRegex.MatchString(tag.Name) {
-					continue
-				}
-
-				timeToCompare := tag.TagDate
-				if timeToCompare == nil {
-					timeToCompare = tag.LastCommitDate
-				}
-				if timeToCompare.Before(time.Now().Add(-staleThreshold)) {
-					filteredTags = append(filteredTags, tag)
-				}
-			}
-			view[repo] = filteredTags
+			mu.Lock()
+			defer mu.Unlock()
+			view[target.String()] = tags
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		viewTargets = indexTargets(targets)
 		out = view
 		return nil
 	},
@@ -60,25 +45,30 @@ var staleTagsCmd = &cobra.Command{
 			return fmt.Errorf("no results found")
 		}
 		view := out.(map[string][]*api.GitHubRef)
+		if len(stalePlanPath) != 0 {
+			return planStaleRefs(cmd, api.TagRefType, view)
+		}
 		if remove {
+			opts, err := staleFlagOptions()
+			if err != nil {
+				return err
+			}
+			var pending removals
 			for repo, tags := range view {
-				if !force {
-					if !helpers.Prompt(fmt.Sprintf("Delete [%d] tags in repo [%v]?", len(tags), repo)) {
-						continue
-					}
-				}
-
-				for _, tag := range tags {
-					if err := ghApi.DeleteRefs(cmd.Context(), tag.Id); err != nil {
-						return fmt.Errorf("unable to delete tags: %v. error: %v", tag.Name, err)
-					}
-				}
+				target := viewTargets[repo]
+				pending.addRefs(target, api.TagRefType, confirmRefs(target.name, api.TagRefType, tags, opts))
 			}
+			return pending.carryOut(cmd.Context())
 		}
 		return nil
 	},
 }
 
 func init() {
+	staleTagsCmd.PersistentFlags().IntVar(&keepLatest, "keep-latest", 0, "If provided, the latest N semver tags of every major or minor line are kept regardless of their age")
+	staleTagsCmd.PersistentFlags().StringVar(&keepLine, "keep-per", minorRetentionLine, "The semver line used by [keep-latest]. Supported values are: major, minor")
+	staleTagsCmd.PersistentFlags().BoolVar(&keepReleased, "keep-released", false, "If specified, tags with a GitHub Release are kept regardless of their age")
+	staleTagsCmd.PersistentFlags().DurationVar(&prereleaseThreshold, "prerelease-threshold", 0, "If provided, pre-release tags (e.g. -rc, -beta) older than this value are stale regardless of the [threshold]")
+	staleTagsCmd.PersistentFlags().StringVar(&tagSortBy, "sort", nameTagSort, "The order of the stale tags. Supported values are: name, date, semver")
 	staleTagsCmd.PersistentFlags().StringVar(&excludePattern, "exclude", "", "If provided, it will be used to exclude tags that match the pattern (regexp)")
 }
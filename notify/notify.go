@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"text/template"
+	"time"
+)
+
+type Action = string
+
+const (
+	DeleteAction Action = "delete"
+	CloseAction         = "close"
+	// RemoveAction closes the PRs & deletes the refs of the event
+	RemoveAction = "remove"
+)
+
+type ItemKind = string
+
+const (
+	BranchItem ItemKind = "branch"
+	TagItem             = "tag"
+	PRItem              = "pr"
+)
+
+const _unknownAuthor = "unknown"
+
+// _defaultTimeout bounds the notification requests so that an unresponsive endpoint cannot hang the run.
+const _defaultTimeout = 30 * time.Second
+
+// DefaultTemplate is the message listing the affected items grouped by repository & author.
+const DefaultTemplate = `gh-tidy is about to {{.Action}} {{len .Items}} item(s):
+{{range .Groups}}
+{{.Repository}}
+{{range .Authors}}  {{.Author}}:
+{{range .Items}}    - {{.Kind}} {{.Name}}{{if .Number}} #{{.Number}}{{end}}{{with .Date}} (last activity {{.Format "2006-01-02"}}){{end}}
+{{end}}{{end}}{{end}}`
+
+// Item is a ref or PR affected by a destructive operation.
+type Item struct {
+	Repository string     `json:"repository"`
+	Kind       ItemKind   `json:"kind"`
+	Name       string     `json:"name"`
+	Number     int        `json:"number,omitempty"`
+	Url        string     `json:"url,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+}
+
+// Event describes a destructive operation about to be carried out.
+type Event struct {
+	Action Action  `json:"action"`
+	Items  []*Item `json:"items"`
+}
+
+type Group struct {
+	Repository string
+	Authors    []*AuthorGroup
+}
+
+type AuthorGroup struct {
+	Author string
+	Items  []*Item
+}
+
+// Groups returns the event items grouped by repository & author, both sorted by name.
+func (e *Event) Groups() []*Group {
+	index := make(map[string]map[string][]*Item)
+	for _, item := range e.Items {
+		author := item.Author
+		if len(author) == 0 {
+			author = _unknownAuthor
+		}
+		if index[item.Repository] == nil {
+			index[item.Repository] = make(map[string][]*Item)
+		}
+		index[item.Repository][author] = append(index[item.Repository][author], item)
+	}
+
+	var out []*Group
+	for repo, authors := range index {
+		group := &Group{Repository: repo}
+		for author, items := range authors {
+			group.Authors = append(group.Authors, &AuthorGroup{Author: author, Items: items})
+		}
+		sort.Slice(group.Authors, func(i, j int) bool {
+			return group.Authors[i].Author < group.Authors[j].Author
+		})
+		out = append(out, group)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Repository < out[j].Repository
+	})
+	return out
+}
+
+// Notifier sends a notification about an event before it is carried out.
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) error
+}
+
+// Notifiers notifies all of its notifiers, reporting every failure.
+type Notifiers []Notifier
+
+func (n Notifiers) Notify(ctx context.Context, event *Event) error {
+	var err error
+	for _, notifier := range n {
+		err = errors.Join(err, notifier.Notify(ctx, event))
+	}
+	return err
+}
+
+type options struct {
+	client   *http.Client
+	template *template.Template
+}
+
+type Option = func(*options)
+
+func WithHttpClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithTemplate overrides the DefaultTemplate used to render the notification message.
+func WithTemplate(tpl *template.Template) Option {
+	return func(o *options) {
+		o.template = tpl
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		client:   &http.Client{Timeout: _defaultTimeout},
+		template: template.Must(template.New("message").Parse(DefaultTemplate)),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) render(event *Event) (string, error) {
+	var buf bytes.Buffer
+	if err := o.template.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
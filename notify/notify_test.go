@@ -0,0 +1,179 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pcanilho/gh-tidy/notify"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+var t0 = time.Date(2023, 8, 29, 0, 0, 0, 0, time.UTC)
+
+func event() *notify.Event {
+	return &notify.Event{Action: notify.DeleteAction, Items: []*notify.Item{
+		{Repository: "x/b", Kind: notify.BranchItem, Name: "feature", Author: "octocat", Date: &t0},
+		{Repository: "x/a", Kind: notify.PRItem, Name: "fix", Number: 7, Author: "mona"},
+		{Repository: "x/a", Kind: notify.TagItem, Name: "v1.0.0"},
+	}}
+}
+
+func TestEvent_Groups(t *testing.T) {
+	groups := event().Groups()
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "x/a", groups[0].Repository)
+	assert.Equal(t, "mona", groups[0].Authors[0].Author)
+	assert.Equal(t, "unknown", groups[0].Authors[1].Author)
+	assert.Equal(t, "x/b", groups[1].Repository)
+	assert.Equal(t, "feature", groups[1].Authors[0].Items[0].Name)
+}
+
+func TestWebhook(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		payload = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("webhook-valid", func(ti *testing.T) {
+		assert.NoError(ti, notify.NewWebhook(server.URL, notify.WithHttpClient(server.Client())).Notify(context.Background(), event()))
+		assert.Equal(ti, "delete", payload["action"])
+		assert.Len(ti, payload["items"], 3)
+		assert.Equal(ti, `gh-tidy is about to delete 3 item(s):
+
+x/a
+  mona:
+    - pr fix #7
+  unknown:
+    - tag v1.0.0
+
+x/b
+  octocat:
+    - branch feature (last activity 2023-08-29)
+`, payload["message"])
+	})
+	t.Run("webhook-failed", func(ti *testing.T) {
+		assert.Error(ti, notify.NewWebhook(server.URL+"/fail").Notify(context.Background(), event()))
+	})
+	t.Run("slack-template", func(ti *testing.T) {
+		tpl := template.Must(template.New("message").Parse(`{{.Action}}: {{len .Items}}`))
+		assert.NoError(ti, notify.NewSlack(server.URL, notify.WithTemplate(tpl)).Notify(context.Background(), event()))
+		assert.Equal(ti, map[string]any{"text": "delete: 3"}, payload)
+	})
+	t.Run("notifiers-join-errors", func(ti *testing.T) {
+		n := notify.Notifiers{notify.NewSlack(server.URL + "/fail"), notify.NewWebhook(server.URL + "/fail")}
+		err := n.Notify(context.Background(), event())
+		assert.Error(ti, err)
+		assert.Equal(ti, 2, strings.Count(err.Error(), "unable to notify"))
+	})
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(t, listener, received)
+
+	n := notify.NewSMTP(listener.Addr().String(), "tidy@x.y", []string{"a@x.y", "b@x.y"}, nil)
+	assert.NoError(t, n.Notify(context.Background(), event()))
+
+	mail := <-received
+	assert.Contains(t, mail, "RCPT TO:<a@x.y>")
+	assert.Contains(t, mail, "RCPT TO:<b@x.y>")
+	assert.Contains(t, mail, "Subject: [gh-tidy] about to delete 3 item(s)")
+	assert.Contains(t, mail, "    - branch feature (last activity 2023-08-29)")
+
+	assert.Error(t, notify.NewSMTP(listener.Addr().String(), "tidy@x.y", nil, nil).Notify(context.Background(), event()))
+}
+
+func TestSMTP_Auth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go serveSMTP(t, listener, make(chan string, 1))
+
+	// the stand-in server does not advertise AUTH, so the configured credentials cannot be used
+	auth := smtp.PlainAuth("", "user", "password", "127.0.0.1")
+	err = notify.NewSMTP(listener.Addr().String(), "tidy@x.y", []string{"a@x.y"}, auth).Notify(context.Background(), event())
+	assert.ErrorContains(t, err, "the server does not support AUTH")
+}
+
+func TestSMTP_Cancelled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	// the server accepts the connection but never greets the client
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = notify.NewSMTP(listener.Addr().String(), "tidy@x.y", []string{"a@x.y"}, nil).Notify(ctx, event())
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+// serveSMTP is a minimal SMTP stand-in recording the commands & data of a single session.
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var session strings.Builder
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, err := fmt.Fprintf(conn, "%v\r\n", line)
+		assert.NoError(t, err)
+	}
+	reply("220 localhost")
+	var data bool
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		session.WriteString(line)
+		switch {
+		case data:
+			if line == ".\r\n" {
+				data = false
+				reply("250 OK")
+			}
+		case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(line, "DATA"):
+			data = true
+			reply("354 go ahead")
+		case strings.HasPrefix(line, "QUIT"):
+			reply("221 bye")
+			received <- session.String()
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
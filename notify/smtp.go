@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP emails the rendered message to its recipients.
+type SMTP struct {
+	addr string
+	from string
+	to   []string
+	auth smtp.Auth
+	*options
+}
+
+// NewSMTP creates an SMTP notifier sending through the server at addr ('host:port'). The auth is optional.
+func NewSMTP(addr, from string, to []string, auth smtp.Auth, opts ...Option) *SMTP {
+	return &SMTP{addr: addr, from: from, to: to, auth: auth, options: newOptions(opts...)}
+}
+
+func (s *SMTP) Notify(ctx context.Context, event *Event) error {
+	if len(s.to) == 0 {
+		return fmt.Errorf("at least one email recipient must be specified")
+	}
+	message, err := s.render(event)
+	if err != nil {
+		return fmt.Errorf("unable to render the email message. error: %v", err)
+	}
+
+	var mail strings.Builder
+	fmt.Fprintf(&mail, "From: %v\r\n", s.from)
+	fmt.Fprintf(&mail, "To: %v\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&mail, "Subject: [gh-tidy] about to %v %d item(s)\r\n", event.Action, len(event.Items))
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	mail.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+
+	if err = s.send(ctx, []byte(mail.String())); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("unable to notify: %v. error: %v", s.addr, err)
+	}
+	return nil
+}
+
+// send carries out the same exchange as smtp.SendMail, aborting it once the context is done.
+func (s *SMTP) send(ctx context.Context, mail []byte) error {
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return err
+	}
+	dialer := &net.Dialer{Timeout: _defaultTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// bounds the whole exchange, as a server may accept the connection and then stall
+	if err = conn.SetDeadline(time.Now().Add(_defaultTimeout)); err != nil {
+		return err
+	}
+	// unblocks the exchange when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		// the credentials are never silently ignored
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("the server does not support AUTH")
+		}
+		if err = c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(mail); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
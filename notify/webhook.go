@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook posts the event as JSON, along with its rendered message, to a generic webhook.
+type Webhook struct {
+	url string
+	*options
+}
+
+func NewWebhook(url string, opts ...Option) *Webhook {
+	return &Webhook{url: url, options: newOptions(opts...)}
+}
+
+func (w *Webhook) Notify(ctx context.Context, event *Event) error {
+	message, err := w.render(event)
+	if err != nil {
+		return fmt.Errorf("unable to render the webhook message. error: %v", err)
+	}
+	return post(ctx, w.client, w.url, struct {
+		*Event
+		Message string `json:"message"`
+	}{event, message})
+}
+
+// Slack posts the rendered message to a Slack-compatible incoming webhook.
+type Slack struct {
+	url string
+	*options
+}
+
+func NewSlack(url string, opts ...Option) *Slack {
+	return &Slack{url: url, options: newOptions(opts...)}
+}
+
+func (s *Slack) Notify(ctx context.Context, event *Event) error {
+	message, err := s.render(event)
+	if err != nil {
+		return fmt.Errorf("unable to render the slack message. error: %v", err)
+	}
+	return post(ctx, s.client, s.url, map[string]string{"text": message})
+}
+
+func post(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to notify: %v. error: %v", url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		content, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unable to notify: %v. error: unexpected status [%v] %s", url, res.Status, content)
+	}
+	return nil
+}